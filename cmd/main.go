@@ -8,13 +8,22 @@ import (
 	"net"
 	"net/http"
 	"time"
+	"weather_service/internal/cache"
+	"weather_service/internal/changefeed"
 	"weather_service/internal/config"
-	_ "weather_service/internal/database"
-	"weather_service/internal/database/postgres"
+	"weather_service/internal/correction"
+	"weather_service/internal/database"
+	"weather_service/internal/database/backend"
 	"weather_service/internal/geocoding"
+	"weather_service/internal/handlers"
 	"weather_service/internal/handlers/cities"
 	"weather_service/internal/handlers/forecasts"
-	"weather_service/pkg/client"
+	"weather_service/internal/handlers/observations"
+	"weather_service/internal/handlers/rankings"
+	"weather_service/internal/handlers/verification"
+	"weather_service/internal/openapi"
+	"weather_service/internal/partitioning"
+	"weather_service/internal/retention"
 )
 
 func main() {
@@ -32,17 +41,17 @@ func main() {
 	}
 
 	router := httprouter.New()
+	//unknown routes and panics are answered with the same problem body as handler errors
+	router.NotFound = http.HandlerFunc(handlers.NotFound)
+	router.MethodNotAllowed = http.HandlerFunc(handlers.MethodNotAllowed)
+	router.PanicHandler = handlers.Panic
 	log.Println("Router created successfully")
 
-	//postgres db client
-	pgclient, err := client.NewClient(context.Background(), *cfg)
+	//repository for configured storage
+	repo, err := backend.NewRepository(context.Background(), *cfg)
 	if err != nil {
-		log.Panicln("Can not connect DB: error", err)
+		log.Panicln("Can not create repository: error", err)
 	}
-	log.Println("DB client connected successfully")
-
-	//postgres repository
-	repo := postgres.NewPostgresRepository(pgclient)
 	log.Println("Repository created successfully")
 
 	//Saving cities coordinates to table
@@ -62,24 +71,97 @@ func main() {
 	}
 	log.Println("Table citiesList created and filled up successfully")
 
+	//Partition maintenance job init
+	if partitions, ok := repo.(database.PartitionManager); ok && cfg.Partitioning.Interval > 0 {
+		maintainer := partitioning.NewMaintainer(partitions, cfg.Partitioning.MonthsAhead, time.Duration(cfg.Partitioning.Interval)*time.Second)
+		maintainer.Start()
+		log.Println("Partition maintainer started")
+	}
+
+	//Forecast change feed init, it drops changed cities from the summaries cache of forecast handlers
+	var forecastsRepo database.Repository = repo
+	if listener, ok := repo.(database.ChangeListener); ok && cfg.ChangeFeed.Enabled && cfg.ChangeFeed.CacheTTL > 0 {
+		feed := changefeed.NewFeed(listener)
+		summaries := cache.NewSummaries(repo, time.Duration(cfg.ChangeFeed.CacheTTL)*time.Second)
+		summaries.Follow(feed)
+		forecastsRepo = summaries
+		feed.Start()
+		log.Println("Forecast change feed started")
+	}
+
 	//Weather updater init
 	updater := geocoding.NewWeatherUpdater(cfg.API.Key, repo, time.Duration(cfg.API.Interval)*time.Second)
+
+	//Bias correction init
+	if cfg.Correction.Enabled {
+		corrector := correction.NewCorrector(repo, time.Duration(cfg.Correction.WindowDays)*24*time.Hour, cfg.Correction.MinSamples, time.Duration(cfg.Correction.Interval)*time.Second)
+		if cfg.Correction.Version > 0 {
+			found, err := corrector.Load(context.Background(), cfg.Correction.Version)
+			if err != nil || !found {
+				log.Fatal("Can not load bias correction version ", cfg.Correction.Version, ": error ", err)
+			}
+		} else {
+			err = corrector.Retrain(context.Background())
+			if err != nil {
+				log.Println("Can not train bias correction: error", err)
+			}
+			if cfg.Correction.Interval > 0 {
+				corrector.Start()
+			}
+		}
+		updater.Use(corrector)
+		log.Println("Bias correction enabled")
+	}
+
 	updater.Start()
 	updater.UpdateWeather()
 	log.Println("Weather updater started")
 
+	//Observation updater init
+	if cfg.API.ObservationInterval > 0 {
+		observationUpdater := geocoding.NewObservationUpdater(cfg.API.Key, repo, time.Duration(cfg.API.ObservationInterval)*time.Second)
+		observationUpdater.Start()
+		observationUpdater.UpdateObservations()
+		log.Println("Observation updater started")
+	}
+
+	//Retention purge job init
+	if cfg.Retention.Interval > 0 {
+		purger := retention.NewPurger(repo, cfg.Retention.RawDays, cfg.Retention.DailyDays, cfg.Retention.BatchSize, time.Duration(cfg.Retention.Interval)*time.Second)
+		purger.Start()
+		log.Println("Retention purger started")
+	}
+
 	router.ServeFiles("/static/*filepath", http.Dir("static"))
 
 	citiesHandler := cities.NewHandler(repo)
 	citiesHandler.Register(router)
 
-	forecastsHandler := forecasts.NewHandler(repo)
+	forecastsHandler := forecasts.NewHandler(forecastsRepo)
 	forecastsHandler.Register(router)
-	start(router, cfg)
+
+	observationsHandler := observations.NewHandler(repo)
+	observationsHandler.Register(router)
+
+	verificationHandler := verification.NewHandler(repo)
+	verificationHandler.Register(router)
+
+	rankingsHandler := rankings.NewHandler(repo)
+	rankingsHandler.Register(router)
+
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Fatal("Can not load OpenAPI document: error ", err)
+	}
+	openapiHandler := openapi.NewHandler(spec)
+	openapiHandler.Register(router)
+
+	//parameters are checked against OpenAPI document before routing
+	start(handlers.RequestID(openapiHandler.Validate(router)), cfg)
 
 }
 
-func start(router *httprouter.Router, cfg *config.Config) {
+func start(handler http.Handler, cfg *config.Config) {
 	var listener net.Listener
 
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.Server.Port))
@@ -90,7 +172,7 @@ func start(router *httprouter.Router, cfg *config.Config) {
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.Server.Port),
-		Handler: router}
+		Handler: handler}
 
 	err = server.Serve(listener)
 	if err != nil {
@@ -0,0 +1,120 @@
+// Package cache keeps daily summaries read by forecast handlers in memory of this instance
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+	"weather_service/internal/changefeed"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+)
+
+// followBuffer - changes waiting for invalidation before the feed drops them
+const followBuffer = 256
+
+// entry - cached result of one city, zero fields are not cached yet
+type entry struct {
+	summaries []models.DailySummary
+	short     *models.ShortForecast
+	expires   time.Time
+}
+
+// Summaries - database.Repository caching daily summaries and short forecast of every city for ttl.
+// A city is dropped from the cache when its forecast changes, see Follow, ttl covers the change of local day
+type Summaries struct {
+	database.Repository
+	ttl time.Duration
+
+	mu     sync.Mutex
+	cities map[int]*entry
+	// versions - number of invalidations of every city, results read before an invalidation are not cached
+	versions map[int]int
+}
+
+// NewSummaries - constructor for Summaries struct
+func NewSummaries(repo database.Repository, ttl time.Duration) *Summaries {
+	return &Summaries{
+		Repository: repo,
+		ttl:        ttl,
+		cities:     make(map[int]*entry),
+		versions:   make(map[int]int),
+	}
+}
+
+// GetDailySummaries returns cached daily summaries of concrete city or reads them from repository
+func (s *Summaries) GetDailySummaries(ctx context.Context, cityID int) ([]models.DailySummary, error) {
+	e, version := s.get(cityID)
+	if e != nil && e.summaries != nil {
+		return e.summaries, nil
+	}
+	summaries, err := s.Repository.GetDailySummaries(ctx, cityID)
+	if err != nil {
+		return nil, err
+	}
+	s.put(cityID, version, func(e *entry) { e.summaries = summaries })
+	return summaries, nil
+}
+
+// GetShortForecastByCityID returns cached short forecast of concrete city or reads it from repository
+func (s *Summaries) GetShortForecastByCityID(ctx context.Context, cityID int) (*models.ShortForecast, error) {
+	e, version := s.get(cityID)
+	if e != nil && e.short != nil {
+		return e.short, nil
+	}
+	short, err := s.Repository.GetShortForecastByCityID(ctx, cityID)
+	if err != nil {
+		return nil, err
+	}
+	s.put(cityID, version, func(e *entry) { e.short = short })
+	return short, nil
+}
+
+// Invalidate drops cached results of concrete city
+func (s *Summaries) Invalidate(cityID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cities, cityID)
+	s.versions[cityID]++
+}
+
+// Follow drops cities from the cache when feed reports their changes, until the returned function is called
+func (s *Summaries) Follow(feed *changefeed.Feed) func() {
+	changes, unsubscribe := feed.Subscribe(followBuffer)
+	go func() {
+		for change := range changes {
+			s.Invalidate(change.CityID)
+		}
+	}()
+	return unsubscribe
+}
+
+// get returns copy of the city entry that has not expired yet, or nil, and the number of invalidations of the city
+func (s *Summaries) get(cityID int) (*entry, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.cities[cityID]
+	if !ok || time.Now().After(e.expires) {
+		return nil, s.versions[cityID]
+	}
+	copied := *e
+	return &copied, s.versions[cityID]
+}
+
+// put sets fields of the city entry with set unless the city was invalidated after version was got,
+// a new entry expires after ttl
+func (s *Summaries) put(cityID int, version int, set func(e *entry)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.versions[cityID] != version {
+		return
+	}
+	e, ok := s.cities[cityID]
+	if !ok || time.Now().After(e.expires) {
+		e = &entry{expires: time.Now().Add(s.ttl)}
+		s.cities[cityID] = e
+	}
+	set(e)
+}
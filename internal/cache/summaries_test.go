@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"weather_service/internal/changefeed"
+	"weather_service/internal/database"
+	"weather_service/internal/database/memory"
+	"weather_service/internal/models"
+)
+
+// notifyingRepo is memory repository reporting dates saved by ReplaceCityForecasts to its listener like PostgreSQL does
+type notifyingRepo struct {
+	database.Repository
+	listening chan struct{}
+
+	mu     sync.Mutex
+	handle func(database.ForecastChange)
+}
+
+func (r *notifyingRepo) ListenForecastChanges(ctx context.Context, ready func(), handle func(database.ForecastChange)) error {
+	r.mu.Lock()
+	r.handle = handle
+	r.mu.Unlock()
+	close(r.listening)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *notifyingRepo) ReplaceCityForecasts(ctx context.Context, cityID int, forecasts []models.WeatherInfo, slots []models.ForecastSlot, summaries []models.DailySummary) error {
+	if err := r.Repository.ReplaceCityForecasts(ctx, cityID, forecasts, slots, summaries); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, forecast := range forecasts {
+		if r.handle != nil {
+			r.handle(database.ForecastChange{CityID: cityID, Date: forecast.Date.Format("2006-01-02")})
+		}
+	}
+	return nil
+}
+
+func TestSummariesFollowFeed(t *testing.T) {
+	ctx := context.Background()
+	repo := &notifyingRepo{Repository: memory.NewMemoryRepository(), listening: make(chan struct{})}
+	city := models.City{Name: "Berlin", Country: "DE"}
+	if err := repo.CreateCity(ctx, &city); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	save := func(temp float64) {
+		forecasts := []models.WeatherInfo{{Temp: temp, Date: today}}
+		summaries := []models.DailySummary{{Date: today, TempMidday: temp}}
+		if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, nil, summaries); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(20)
+
+	feed := changefeed.NewFeed(repo)
+	cached := NewSummaries(repo, time.Hour)
+	defer cached.Follow(feed)()
+	feed.Start()
+	defer feed.Stop()
+	<-repo.listening
+
+	if summaries, err := cached.GetDailySummaries(ctx, city.ID); err != nil || summaries[0].TempMidday != 20 {
+		t.Fatalf("Expected summaries from repository, got %+v, %v", summaries, err)
+	}
+	// write that is not reported stays unseen until ttl
+	if err := repo.Repository.ReplaceCityForecasts(ctx, city.ID, []models.WeatherInfo{{Temp: 21, Date: today}}, nil, []models.DailySummary{{Date: today, TempMidday: 21}}); err != nil {
+		t.Fatal(err)
+	}
+	if summaries, _ := cached.GetDailySummaries(ctx, city.ID); summaries[0].TempMidday != 20 {
+		t.Errorf("Expected cached summaries, got %+v", summaries)
+	}
+
+	// a forecast write reaches the cache through the feed
+	save(25)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		summaries, err := cached.GetDailySummaries(ctx, city.ID)
+		if err == nil && summaries[0].TempMidday == 25 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected summaries to be read again after change, got %+v, %v", summaries, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if short, err := cached.GetShortForecastByCityID(ctx, city.ID); err != nil || short.AvgTemp != 25 {
+		t.Errorf("Expected fresh short forecast, got %+v, %v", short, err)
+	}
+}
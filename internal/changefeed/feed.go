@@ -0,0 +1,101 @@
+package changefeed
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"weather_service/internal/database"
+)
+
+// maxRetryDelay - the longest pause before listening again after connection failure
+const maxRetryDelay = time.Minute
+
+// Feed - delivers forecast changes made by any instance to subscribers of this instance
+type Feed struct {
+	listener database.ChangeListener
+	cancel   context.CancelFunc
+
+	mu          sync.Mutex
+	subscribers map[int]chan database.ForecastChange
+	nextID      int
+}
+
+// NewFeed - constructor for Feed struct
+func NewFeed(listener database.ChangeListener) *Feed {
+	return &Feed{
+		listener:    listener,
+		subscribers: make(map[int]chan database.ForecastChange),
+	}
+}
+
+// Subscribe returns channel of changes with room for buffer changes and function to unsubscribe.
+// Changes are dropped for a subscriber that does not keep up, so it must not block for long
+func (f *Feed) Subscribe(buffer int) (<-chan database.ForecastChange, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := f.nextID
+	f.nextID++
+	changes := make(chan database.ForecastChange, buffer)
+	f.subscribers[id] = changes
+
+	return changes, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subscribers[id]; ok {
+			delete(f.subscribers, id)
+			close(changes)
+		}
+	}
+}
+
+// publish sends change to every subscriber without waiting
+func (f *Feed) publish(change database.ForecastChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, changes := range f.subscribers {
+		select {
+		case changes <- change:
+		default:
+			log.Println("Forecast change dropped for slow subscriber:", change)
+		}
+	}
+}
+
+// Start - starts listening in background, listening is resumed with growing delay after connection failures
+func (f *Feed) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+
+	go func() {
+		delay := time.Second
+		for {
+			started := time.Now()
+			err := f.listener.ListenForecastChanges(ctx, nil, f.publish)
+			if ctx.Err() != nil {
+				return
+			}
+			// connection that worked for a while starts the backoff again
+			if time.Since(started) > maxRetryDelay {
+				delay = time.Second
+			}
+			log.Println("Can not listen forecast changes, retry in", delay, "error", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxRetryDelay)
+		}
+	}()
+}
+
+// Stop - stops listening
+func (f *Feed) Stop() {
+	if f.cancel != nil {
+		f.cancel()
+	}
+}
@@ -0,0 +1,74 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"weather_service/internal/database"
+)
+
+// fakeListener reports changes it gets from changes channel, the first call fails like lost connection
+type fakeListener struct {
+	calls   int
+	changes chan database.ForecastChange
+}
+
+func (l *fakeListener) ListenForecastChanges(ctx context.Context, ready func(), handle func(database.ForecastChange)) error {
+	l.calls++
+	if l.calls == 1 {
+		return errors.New("connection refused")
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case change := <-l.changes:
+			handle(change)
+		}
+	}
+}
+
+func TestFeed(t *testing.T) {
+	listener := &fakeListener{changes: make(chan database.ForecastChange)}
+	feed := NewFeed(listener)
+
+	first, _ := feed.Subscribe(1)
+	second, unsubscribe := feed.Subscribe(1)
+	unsubscribe()
+	// unsubscribing twice is fine
+	unsubscribe()
+
+	feed.Start()
+	defer feed.Stop()
+
+	change := database.ForecastChange{CityID: 1, Date: "2024-07-11"}
+	select {
+	case listener.changes <- change:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected feed to listen again after failure")
+	}
+
+	select {
+	case got := <-first:
+		if got != change {
+			t.Errorf("Expected %+v, got %+v", change, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected change to be delivered to subscriber")
+	}
+	if _, ok := <-second; ok {
+		t.Errorf("Expected channel of unsubscribed subscriber to be closed")
+	}
+
+	// subscriber with full buffer does not block others
+	listener.changes <- change
+	listener.changes <- change
+	third, _ := feed.Subscribe(1)
+	listener.changes <- change
+	select {
+	case <-third:
+	case <-time.After(time.Second):
+		t.Fatal("Expected change to be delivered while other subscriber is full")
+	}
+}
@@ -5,7 +5,8 @@ import (
 )
 
 type Config struct {
-	Server struct {
+	Storage string `mapstructure:"storage"`
+	Server  struct {
 		Port string `mapstructure:"port"`
 	} `mapstructure:"server"`
 	Database struct {
@@ -14,11 +15,57 @@ type Config struct {
 		User     string `mapstructure:"user"`
 		Password string `mapstructure:"password"`
 		DBName   string `mapstructure:"dbname"`
+		// TLS, empty values keep libpq defaults
+		SSLMode     string `mapstructure:"sslmode"`
+		SSLRootCert string `mapstructure:"sslrootcert"`
+		SSLCert     string `mapstructure:"sslcert"`
+		SSLKey      string `mapstructure:"sslkey"`
+		// pool settings, durations are in seconds, 0 keeps pgxpool defaults
+		ApplicationName   string `mapstructure:"application_name"`
+		MaxConns          int    `mapstructure:"max_conns"`
+		MinConns          int    `mapstructure:"min_conns"`
+		MaxConnLifetime   int    `mapstructure:"max_conn_lifetime"`
+		MaxConnIdleTime   int    `mapstructure:"max_conn_idle_time"`
+		HealthCheckPeriod int    `mapstructure:"health_check_period"`
+		StatementTimeout  int    `mapstructure:"statement_timeout"`
+		ConnectTimeout    int    `mapstructure:"connect_timeout"`
+		ConnectRetry      int    `mapstructure:"connect_retry"`
+		MigrateTimeout    int    `mapstructure:"migrate_timeout"`
+		Replica           struct {
+			DSN           string `mapstructure:"dsn"`
+			MaxLag        int    `mapstructure:"max_lag"`
+			CheckInterval int    `mapstructure:"check_interval"`
+		} `mapstructure:"replica"`
 	} `mapstructure:"database"`
+	SQLite struct {
+		Path string `mapstructure:"path"`
+	} `mapstructure:"sqlite"`
 	API struct {
-		Key      string `mapstructure:"key"`
-		Interval int    `mapstructure:"interval"`
+		Key                 string `mapstructure:"key"`
+		Interval            int    `mapstructure:"interval"`
+		ObservationInterval int    `mapstructure:"observation_interval"`
 	} `mapstructure:"api"`
+	Retention struct {
+		RawDays   int `mapstructure:"raw_days"`
+		DailyDays int `mapstructure:"daily_days"`
+		Interval  int `mapstructure:"interval"`
+		BatchSize int `mapstructure:"batch_size"`
+	} `mapstructure:"retention"`
+	Partitioning struct {
+		MonthsAhead int `mapstructure:"months_ahead"`
+		Interval    int `mapstructure:"interval"`
+	} `mapstructure:"partitioning"`
+	ChangeFeed struct {
+		Enabled  bool `mapstructure:"enabled"`
+		CacheTTL int  `mapstructure:"cache_ttl"`
+	} `mapstructure:"changefeed"`
+	Correction struct {
+		Enabled    bool `mapstructure:"enabled"`
+		Version    int  `mapstructure:"version"`
+		WindowDays int  `mapstructure:"window_days"`
+		MinSamples int  `mapstructure:"min_samples"`
+		Interval   int  `mapstructure:"interval"`
+	} `mapstructure:"correction"`
 }
 
 // LoadConfig loads config file from path and returns Config struct or error
@@ -0,0 +1,188 @@
+package correction
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+	"weather_service/internal/verification"
+)
+
+// offsetKey identifies a correction by city, hour of valid time (UTC) and lead time
+type offsetKey struct {
+	cityID    int
+	hour      int
+	leadHours int
+}
+
+// Train learns mean temperature error (forecast - observed) for every city, hour of day and lead time
+// from pairs. Groups with less than minSamples pairs are skipped. The result depends only on the pairs,
+// so training on the same window again gives the same offsets
+func Train(pairs []models.VerificationPair, from, to time.Time, minSamples int) models.BiasCorrection {
+	sums := make(map[offsetKey]float64)
+	counts := make(map[offsetKey]int)
+	for _, p := range pairs {
+		lead := verification.LeadBucket(p.Lead())
+		if lead < 0 {
+			continue
+		}
+		key := offsetKey{cityID: p.CityID, hour: p.ValidAt.UTC().Hour(), leadHours: int(lead / time.Hour)}
+		sums[key] += p.ForecastTemp - p.ObservedTemp
+		counts[key]++
+	}
+
+	offsets := make([]models.BiasOffset, 0)
+	for key, n := range counts {
+		if n < minSamples {
+			continue
+		}
+		offsets = append(offsets, models.BiasOffset{
+			CityID:    key.cityID,
+			Hour:      key.hour,
+			LeadHours: key.leadHours,
+			Bias:      sums[key] / float64(n),
+			Samples:   n,
+		})
+	}
+
+	// sort offsets by city, hour and lead time
+	sort.Slice(offsets, func(i, j int) bool {
+		if offsets[i].CityID != offsets[j].CityID {
+			return offsets[i].CityID < offsets[j].CityID
+		}
+		if offsets[i].Hour != offsets[j].Hour {
+			return offsets[i].Hour < offsets[j].Hour
+		}
+		return offsets[i].LeadHours < offsets[j].LeadHours
+	})
+
+	return models.BiasCorrection{
+		From:       from,
+		To:         to,
+		MinSamples: minSamples,
+		Offsets:    offsets,
+	}
+}
+
+// Corrector - struct for learning temperature corrections every {interval} seconds and applying them to new forecasts
+type Corrector struct {
+	repo       database.Repository
+	window     time.Duration
+	minSamples int
+	interval   time.Duration
+	ticker     *time.Ticker
+
+	mu      sync.RWMutex
+	version int
+	offsets map[offsetKey]float64
+}
+
+// NewCorrector - constructor for Corrector struct, corrections are learned from the last {window} of pairs.
+// Interval 0 disables retraining, like for a pinned version
+func NewCorrector(repo database.Repository, window time.Duration, minSamples int, interval time.Duration) *Corrector {
+	return &Corrector{
+		repo:       repo,
+		window:     window,
+		minSamples: minSamples,
+		interval:   interval,
+	}
+}
+
+// Load uses stored corrections of concrete version, version 0 means the latest one.
+// Returns false if there is no such version
+func (c *Corrector) Load(ctx context.Context, version int) (bool, error) {
+	correction, err := c.repo.GetBiasCorrection(ctx, version)
+	if err != nil || correction == nil {
+		return false, err
+	}
+	c.use(correction)
+	return true, nil
+}
+
+// Retrain learns corrections from the last {window} of forecast and observation pairs, saves them as a new version and uses it
+func (c *Corrector) Retrain(ctx context.Context) error {
+	to := time.Now().UTC().Truncate(time.Hour)
+	from := to.Add(-c.window)
+
+	pairs, err := c.repo.GetVerificationPairs(ctx, database.VerificationFilter{
+		Provider: models.SourceOpenWeatherMap,
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		return err
+	}
+
+	correction := Train(pairs, from, to, c.minSamples)
+	correction.TrainedAt = time.Now().UTC()
+	if err := c.repo.CreateBiasCorrection(ctx, &correction); err != nil {
+		return err
+	}
+	c.use(&correction)
+
+	log.Printf("Bias correction version %d trained on %d pairs: %d offsets", correction.Version, len(pairs), len(correction.Offsets))
+	return nil
+}
+
+// use replaces corrections applied to new forecasts
+func (c *Corrector) use(correction *models.BiasCorrection) {
+	offsets := make(map[offsetKey]float64, len(correction.Offsets))
+	for _, o := range correction.Offsets {
+		offsets[offsetKey{cityID: o.CityID, hour: o.Hour, leadHours: o.LeadHours}] = o.Bias
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version = correction.Version
+	c.offsets = offsets
+}
+
+// Process sets corrected temperature for forecast slots of a city issued at issuedAt,
+// slots without learned correction stay uncorrected
+func (c *Corrector) Process(cityID int, issuedAt time.Time, slots []models.List) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for i := range slots {
+		lead := verification.LeadBucket(slots[i].DtTime.Sub(issuedAt))
+		key := offsetKey{cityID: cityID, hour: slots[i].DtTime.UTC().Hour(), leadHours: int(lead / time.Hour)}
+		bias, ok := c.offsets[key]
+		if !ok {
+			continue
+		}
+		slots[i].Correction = &models.Correction{
+			Version: c.version,
+			Temp:    slots[i].Main.Temp - bias,
+			Bias:    bias,
+		}
+	}
+}
+
+// Start - starts retraining in background with {interval}, does nothing when interval is not positive
+func (c *Corrector) Start() {
+	if c.interval <= 0 {
+		return
+	}
+	c.ticker = time.NewTicker(c.interval)
+	go func() {
+		defer c.ticker.Stop()
+		for {
+			select {
+			case <-c.ticker.C:
+				if err := c.Retrain(context.Background()); err != nil {
+					log.Println("Can not train bias correction: error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop - stops retraining
+func (c *Corrector) Stop() {
+	if c.ticker != nil {
+		c.ticker.Stop()
+	}
+}
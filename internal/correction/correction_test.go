@@ -0,0 +1,61 @@
+package correction
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+	"weather_service/internal/models"
+)
+
+func TestTrainAndProcess(t *testing.T) {
+	valid := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	pairs := []models.VerificationPair{
+		{CityID: 1, IssuedAt: valid.Add(-24 * time.Hour), ValidAt: valid, ForecastTemp: 22, ObservedTemp: 20},
+		{CityID: 1, IssuedAt: valid, ValidAt: valid.AddDate(0, 0, 1), ForecastTemp: 21, ObservedTemp: 20},
+		{CityID: 1, IssuedAt: valid.Add(-48 * time.Hour), ValidAt: valid, ForecastTemp: 30, ObservedTemp: 20},
+	}
+
+	model := Train(pairs, valid.AddDate(0, 0, -30), valid, 2)
+	if len(model.Offsets) != 1 {
+		t.Fatalf("Expected 1 offset with enough samples, got %+v", model.Offsets)
+	}
+	if o := model.Offsets[0]; o.Hour != 12 || o.LeadHours != 24 || math.Abs(o.Bias-1.5) > 1e-9 {
+		t.Errorf("Expected 1.5 bias at 12h for 24h lead, got %+v", o)
+	}
+
+	if again := Train(pairs, model.From, model.To, 2); !reflect.DeepEqual(again, model) {
+		t.Errorf("Expected training to be reproducible, got %+v and %+v", model, again)
+	}
+
+	model.Version = 3
+	c := &Corrector{}
+	c.use(&model)
+
+	issuedAt := time.Date(2024, 7, 20, 12, 0, 0, 0, time.UTC)
+	slots := []models.List{
+		{DtTime: issuedAt.Add(24 * time.Hour), Main: models.Main{Temp: 25}},
+		{DtTime: issuedAt.Add(27 * time.Hour), Main: models.Main{Temp: 25}},
+	}
+	c.Process(1, issuedAt, slots)
+
+	if slots[0].Correction == nil || slots[0].Correction.Version != 3 || slots[0].Correction.Temp != 23.5 {
+		t.Errorf("Expected corrected temp 23.5 of version 3, got %+v", slots[0].Correction)
+	}
+	if slots[0].Main.Temp != 25 {
+		t.Errorf("Expected raw temp to be kept, got %v", slots[0].Main.Temp)
+	}
+	if slots[1].Correction != nil {
+		t.Errorf("Expected slot without learned correction to stay uncorrected, got %+v", slots[1].Correction)
+	}
+}
+
+func TestCorrectorWithoutRetraining(t *testing.T) {
+	// interval 0 is used with a pinned version, there is nothing to schedule
+	corrector := NewCorrector(nil, 30*24*time.Hour, 2, 0)
+	corrector.Start()
+	corrector.Stop()
+	if corrector.ticker != nil {
+		t.Errorf("Expected no ticker without interval")
+	}
+}
@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"weather_service/internal/config"
+	"weather_service/internal/database"
+	"weather_service/internal/database/memory"
+	"weather_service/internal/database/postgres"
+	"weather_service/internal/database/sqlite"
+	"weather_service/pkg/client"
+)
+
+const (
+	// Postgres stores data in PostgreSQL server, it is used when storage is not set
+	Postgres = "postgres"
+	// SQLite stores data in a local SQLite file, for single-node deployments without PostgreSQL
+	SQLite = "sqlite"
+	// Memory keeps data in process memory, everything is lost on restart
+	Memory = "memory"
+)
+
+// NewRepository creates repository for storage selected in config
+func NewRepository(ctx context.Context, cfg config.Config) (database.Repository, error) {
+	switch cfg.Storage {
+	case Postgres, "":
+		//postgres db client
+		pgclient, err := client.NewClient(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Database.Replica.DSN == "" {
+			return postgres.NewPostgresRepository(pgclient), nil
+		}
+		if cfg.Database.Replica.CheckInterval <= 0 {
+			return nil, fmt.Errorf("replica check_interval must be positive, got %d", cfg.Database.Replica.CheckInterval)
+		}
+		//reads go to replica while it is fresh
+		replica, err := client.NewReplicaClient(ctx, cfg, pgclient)
+		if err != nil {
+			return nil, err
+		}
+		replica.Start()
+		return postgres.NewPostgresRepositoryWithReader(pgclient, replica), nil
+	case SQLite:
+		//sqlite db client
+		db, err := client.NewSQLiteClient(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.NewSQLiteRepository(db), nil
+	case Memory:
+		return memory.NewMemoryRepository(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"weather_service/internal/models"
+)
+
+// Orders of cities list, ties are broken by city id
+const (
+	CitySortName     = "name"
+	CitySortCountry  = "country"
+	CitySortLatitude = "lat"
+)
+
+// ErrInvalidCursor - cursor of cities list is malformed or belongs to another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CityFilter selects a page of cities: cities of Country if it is set, in Sort order (name by default),
+// after the cursor position if After is set, at most Limit cities if it is positive
+type CityFilter struct {
+	Country string
+	Sort    string
+	After   *CityCursor
+	Limit   int
+}
+
+// CityCursor - position of a city in sort order of cities list, the next page starts after it
+type CityCursor struct {
+	Sort     string  `json:"s"`
+	Name     string  `json:"n,omitempty"`
+	Country  string  `json:"c,omitempty"`
+	Latitude float64 `json:"l,omitempty"`
+	ID       int     `json:"i"`
+}
+
+// ValidCitySort reports whether cities list can be ordered by sort
+func ValidCitySort(sort string) bool {
+	return sort == CitySortName || sort == CitySortCountry || sort == CitySortLatitude
+}
+
+// NewCityCursor returns cursor pointing at city in sort order
+func NewCityCursor(sort string, city models.City) CityCursor {
+	cursor := CityCursor{Sort: sort, ID: city.ID}
+	switch sort {
+	case CitySortCountry:
+		cursor.Country = city.Country
+		cursor.Name = city.Name
+	case CitySortLatitude:
+		cursor.Latitude = city.Latitude
+	default:
+		cursor.Name = city.Name
+	}
+	return cursor
+}
+
+// Encode returns opaque URL safe form of cursor
+func (c CityCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCityCursor decodes cursor made by Encode for sort, returns ErrInvalidCursor if it is malformed
+// or was made for another sort order
+func ParseCityCursor(value, sort string) (*CityCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, value)
+	}
+	var cursor CityCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, value)
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor of sort %q used with sort %q", ErrInvalidCursor, cursor.Sort, sort)
+	}
+	return &cursor, nil
+}
+
+// SortKey returns columns of cities table in order of the page and values of the cursor for the same columns
+func (f CityFilter) SortKey() (columns []string, values []interface{}) {
+	var c CityCursor
+	if f.After != nil {
+		c = *f.After
+	}
+	switch f.Sort {
+	case CitySortCountry:
+		return []string{"country", "city", "id"}, []interface{}{c.Country, c.Name, c.ID}
+	case CitySortLatitude:
+		return []string{"lat", "id"}, []interface{}{c.Latitude, c.ID}
+	default:
+		return []string{"city", "id"}, []interface{}{c.Name, c.ID}
+	}
+}
+
+// Less reports whether city a goes before city b in sort order
+func (f CityFilter) Less(a, b models.City) bool {
+	switch f.Sort {
+	case CitySortCountry:
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	case CitySortLatitude:
+		if a.Latitude != b.Latitude {
+			return a.Latitude < b.Latitude
+		}
+	default:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,807 @@
+// Package databasetest contains conformance suite shared by all database.Repository implementations
+package databasetest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+)
+
+// NewRepository returns a new empty repository for one test
+type NewRepository func(t *testing.T) database.Repository
+
+// TestRepository runs conformance suite against repositories returned by newRepo
+func TestRepository(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo database.Repository)
+	}{
+		{"CitiesSortedByName", testCitiesSortedByName},
+		{"ListCitiesPages", testListCitiesPages},
+		{"CreateCityUpsertsOnNameAndCountry", testCreateCityUpserts},
+		{"CreateForecastUpsertsOnCityAndDate", testCreateForecastUpserts},
+		{"CreateForecastUnknownCity", testCreateForecastUnknownCity},
+		{"ReplaceCityForecasts", testReplaceCityForecasts},
+		{"ReplaceCityForecastsIsAtomic", testReplaceCityForecastsIsAtomic},
+		{"ForecastByDateNotFound", testForecastByDateNotFound},
+		{"ForecastByDateInvalidDate", testForecastByDateInvalidDate},
+		{"ForecastByDateTimeMatchesClock", testForecastByDateTimeMatchesClock},
+		{"ForecastRangeCrossesDays", testForecastRangeCrossesDays},
+		{"ShortForecast", testShortForecast},
+		{"ShortForecastNotFound", testShortForecastNotFound},
+		{"DailySummaries", testDailySummaries},
+		{"PartialSummaryKeepsWholeDay", testPartialSummaryKeepsWholeDay},
+		{"CitiesBatchQueries", testCitiesBatchQueries},
+		{"PurgeForecasts", testPurgeForecasts},
+		{"Observations", testObservations},
+		{"VerificationPairs", testVerificationPairs},
+		{"BiasCorrectionVersions", testBiasCorrectionVersions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+}
+
+// createCity creates a city and fails the test on error
+func createCity(t *testing.T, repo database.Repository, name, country string) models.City {
+	t.Helper()
+	city := models.City{Name: name, Country: country, Latitude: 52.52, Longitude: 13.405}
+	if err := repo.CreateCity(context.Background(), &city); err != nil {
+		t.Fatalf("CreateCity(%s): %v", name, err)
+	}
+	return city
+}
+
+// dayForecast returns forecast for the date of day with slots at 09:00, 12:00 and 15:00 UTC
+func dayForecast(day time.Time, temp float64) models.WeatherInfo {
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	forecast := models.WeatherInfo{Temp: temp, Date: date}
+	for _, hour := range []int{9, 12, 15} {
+		dt := date.Add(time.Duration(hour) * time.Hour)
+		forecast.AdditionalInfo = append(forecast.AdditionalInfo, models.List{
+			Dt:      int(dt.Unix()),
+			Main:    models.Main{Temp: temp + float64(hour-12), Humidity: 60},
+			Weather: []models.Weather{{ID: 800, Main: "Clear", Description: "clear sky", Icon: "01d"}},
+			Pop:     0.2,
+			DtTxt:   dt.Format("2006-01-02 15:04:05"),
+			DtTime:  dt,
+		})
+	}
+	return forecast
+}
+
+// daySummary returns UTC daily summary of day with midday temperature temp
+func daySummary(day time.Time, temp float64) models.DailySummary {
+	return models.DailySummary{
+		Date:       time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+		TempMin:    temp - 3,
+		TempMax:    temp + 3,
+		TempMean:   temp,
+		TempMidday: temp,
+		PopMax:     0.2,
+		Condition:  "Clear",
+	}
+}
+
+// createForecast creates forecast and fails the test on error
+func createForecast(t *testing.T, repo database.Repository, forecast models.WeatherInfo, cityID int) {
+	t.Helper()
+	if err := repo.CreateForecast(context.Background(), &forecast, cityID); err != nil {
+		t.Fatalf("CreateForecast(%d, %s): %v", cityID, forecast.Date.Format("2006-01-02"), err)
+	}
+}
+
+func testCitiesSortedByName(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+
+	cities, err := repo.GetAllCities(ctx)
+	if err != nil {
+		t.Fatalf("GetAllCities: %v", err)
+	}
+	if cities == nil || len(cities) != 0 {
+		t.Fatalf("Expected empty non-nil list, got %#v", cities)
+	}
+
+	// cities of the same name in different countries go in order of id
+	for _, c := range [][2]string{{"Прага", "CZ"}, {"Berlin", "US"}, {"Amsterdam", "NL"}, {"Москва", "RU"}, {"Berlin", "DE"}} {
+		createCity(t, repo, c[0], c[1])
+	}
+
+	cities, err = repo.GetAllCities(ctx)
+	if err != nil {
+		t.Fatalf("GetAllCities: %v", err)
+	}
+	want := []string{"Amsterdam", "Berlin", "Berlin", "Москва", "Прага"}
+	if len(cities) != len(want) {
+		t.Fatalf("Expected %d cities, got %d", len(want), len(cities))
+	}
+	for i, name := range want {
+		if cities[i].Name != name {
+			t.Errorf("Expected city %d to be %s, got %s", i, name, cities[i].Name)
+		}
+		if cities[i].ID == 0 {
+			t.Errorf("Expected city %s to have id", cities[i].Name)
+		}
+	}
+	if cities[1].ID > cities[2].ID {
+		t.Errorf("Expected cities of the same name in order of id, got %d before %d", cities[1].ID, cities[2].ID)
+	}
+}
+
+func testListCitiesPages(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	for _, c := range []models.City{
+		{Name: "Berlin", Country: "DE", Latitude: 52.52},
+		{Name: "Paris", Country: "FR", Latitude: 48.85},
+		{Name: "Munich", Country: "DE", Latitude: 48.14},
+		{Name: "Rome", Country: "IT", Latitude: 41.9},
+		{Name: "Hamburg", Country: "DE", Latitude: 53.55},
+		{Name: "Århus", Country: "DK", Latitude: 56.16},
+	} {
+		if err := repo.CreateCity(ctx, &c); err != nil {
+			t.Fatalf("CreateCity(%s): %v", c.Name, err)
+		}
+	}
+
+	tests := []struct {
+		sort    string
+		country string
+		want    string
+	}{
+		// names compare byte-wise, so non-ASCII letters go after ASCII ones
+		{database.CitySortName, "", "Berlin,Hamburg,Munich,Paris,Rome,Århus"},
+		{database.CitySortCountry, "", "Berlin,Hamburg,Munich,Århus,Paris,Rome"},
+		{database.CitySortLatitude, "", "Rome,Munich,Paris,Berlin,Hamburg,Århus"},
+		{database.CitySortLatitude, "DE", "Munich,Berlin,Hamburg"},
+		{database.CitySortName, "ES", ""},
+	}
+	for _, tt := range tests {
+		// walk the list two cities at a time
+		var got []string
+		filter := database.CityFilter{Sort: tt.sort, Country: tt.country, Limit: 2}
+		for page := 0; page < 5; page++ {
+			cities, err := repo.ListCities(ctx, filter)
+			if err != nil {
+				t.Fatalf("ListCities(%+v): %v", filter, err)
+			}
+			for _, city := range cities {
+				got = append(got, city.Name)
+			}
+			if len(cities) < filter.Limit {
+				break
+			}
+			cursor := database.NewCityCursor(tt.sort, cities[len(cities)-1])
+			filter.After = &cursor
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("sort %s country %q: expected %s, got %v", tt.sort, tt.country, tt.want, got)
+		}
+	}
+}
+
+func testCreateCityUpserts(t *testing.T, repo database.Repository) {
+	first := createCity(t, repo, "Berlin", "DE")
+	other := createCity(t, repo, "Berlin", "US")
+
+	again := models.City{Name: "Berlin", Country: "DE", Latitude: 1.5, Longitude: 2.5}
+	if err := repo.CreateCity(context.Background(), &again); err != nil {
+		t.Fatalf("CreateCity duplicate: %v", err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("Expected duplicate city to keep id %d, got %d", first.ID, again.ID)
+	}
+	if other.ID == first.ID {
+		t.Errorf("Expected city with other country to get new id")
+	}
+
+	cities, err := repo.GetAllCities(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllCities: %v", err)
+	}
+	if len(cities) != 2 {
+		t.Fatalf("Expected 2 cities, got %d", len(cities))
+	}
+	for _, c := range cities {
+		if c.ID == first.ID && (c.Latitude != 1.5 || c.Longitude != 2.5) {
+			t.Errorf("Expected coordinates to be refreshed, got %v %v", c.Latitude, c.Longitude)
+		}
+	}
+}
+
+func testCreateForecastUpserts(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	day := time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC)
+
+	createForecast(t, repo, dayForecast(day, 20.9), city.ID)
+	updated := dayForecast(day, 25)
+	updated.AdditionalInfo = updated.AdditionalInfo[:2]
+	createForecast(t, repo, updated, city.ID)
+
+	forecasts, err := repo.GetForecastByCityIDandDate(ctx, city.ID, "2024-07-11")
+	if err != nil {
+		t.Fatalf("GetForecastByCityIDandDate: %v", err)
+	}
+	if len(forecasts) != 1 {
+		t.Fatalf("Expected 1 forecast after duplicate insert, got %d", len(forecasts))
+	}
+	f := forecasts[0]
+	if f.Temp != 25 || len(f.AdditionalInfo) != 2 {
+		t.Errorf("Expected second insert to replace temp and slots, got temp %v and %d slots", f.Temp, len(f.AdditionalInfo))
+	}
+	if f.CityID != city.ID || f.ID == 0 || !f.Date.Equal(day) {
+		t.Errorf("Expected forecast of city %d for %s with id, got %+v", city.ID, day, f)
+	}
+	if !f.AdditionalInfo[1].DtTime.Equal(day.Add(12*time.Hour)) || f.AdditionalInfo[1].Weather[0].Main != "Clear" {
+		t.Errorf("Expected slots to round trip, got %+v", f.AdditionalInfo[1])
+	}
+
+	// temp column is integer
+	createForecast(t, repo, dayForecast(day.AddDate(0, 0, 1), 20.9), city.ID)
+	forecasts, err = repo.GetForecastByCityIDandDate(ctx, city.ID, "2024-07-12")
+	if err != nil {
+		t.Fatalf("GetForecastByCityIDandDate: %v", err)
+	}
+	if len(forecasts) != 1 || forecasts[0].Temp != 20 {
+		t.Errorf("Expected temp to be truncated to 20, got %+v", forecasts)
+	}
+}
+
+func testCreateForecastUnknownCity(t *testing.T, repo database.Repository) {
+	forecast := dayForecast(time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC), 20)
+	if err := repo.CreateForecast(context.Background(), &forecast, 4242); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for unknown city, got %v", err)
+	}
+}
+
+func testReplaceCityForecasts(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	other := createCity(t, repo, "Paris", "FR")
+	day := time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		createForecast(t, repo, dayForecast(day.AddDate(0, 0, i), 20), city.ID)
+	}
+	createForecast(t, repo, dayForecast(day.AddDate(0, 0, 3), 20), other.ID)
+
+	// new run starts a day later and no longer has the last day
+	forecasts := []models.WeatherInfo{dayForecast(day.AddDate(0, 0, 2), 25), dayForecast(day.AddDate(0, 0, 1), 24)}
+	slots := []models.ForecastSlot{{CityID: city.ID, Provider: "a", IssuedAt: day, ValidAt: day.Add(36 * time.Hour), Temp: 24}}
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, slots, nil); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+
+	for date, want := range map[string]float64{"2024-07-11": 20, "2024-07-12": 24, "2024-07-13": 25} {
+		got, err := repo.GetForecastByCityIDandDate(ctx, city.ID, date)
+		if err != nil || len(got) != 1 || got[0].Temp != want {
+			t.Errorf("Expected forecast on %s with temp %v, got %+v, %v", date, want, got, err)
+		}
+	}
+	if _, err := repo.GetForecastByCityIDandDate(ctx, city.ID, "2024-07-14"); !errors.Is(err, database.ErrForecastNotFound) {
+		t.Errorf("Expected day missing in the new run to be deleted, got %v", err)
+	}
+	if _, err := repo.GetForecastByCityIDandDate(ctx, other.ID, "2024-07-14"); err != nil {
+		t.Errorf("Expected forecast of other city to stay, got %v", err)
+	}
+
+	if n, err := repo.PurgeForecastHistory(ctx, day.AddDate(0, 0, 4), 10); err != nil || n != 1 {
+		t.Errorf("Expected issued slot to be saved, purged %d, %v", n, err)
+	}
+}
+
+func testReplaceCityForecastsIsAtomic(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	day := time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC)
+	createForecast(t, repo, dayForecast(day, 20), city.ID)
+	createForecast(t, repo, dayForecast(day.AddDate(0, 0, 1), 20), city.ID)
+
+	// the last slot references unknown city, so the whole update must fail
+	forecasts := []models.WeatherInfo{dayForecast(day, 25)}
+	slots := []models.ForecastSlot{
+		{CityID: city.ID, Provider: "a", IssuedAt: day, ValidAt: day.Add(12 * time.Hour)},
+		{CityID: 4242, Provider: "a", IssuedAt: day, ValidAt: day.Add(12 * time.Hour)},
+	}
+	summaries := []models.DailySummary{daySummary(day, 25)}
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, slots, summaries); !errors.Is(err, database.ErrCityNotFound) {
+		t.Fatalf("Expected ErrCityNotFound, got %v", err)
+	}
+
+	for _, date := range []string{"2024-07-11", "2024-07-12"} {
+		got, err := repo.GetForecastByCityIDandDate(ctx, city.ID, date)
+		if err != nil || len(got) != 1 || got[0].Temp != 20 || len(got[0].AdditionalInfo) != 3 {
+			t.Errorf("Expected old forecast on %s after failed update, got %+v, %v", date, got, err)
+		}
+	}
+	if n, err := repo.PurgeForecastHistory(ctx, day.AddDate(0, 0, 1), 10); err != nil || n != 0 {
+		t.Errorf("Expected no issued slots after failed update, purged %d, %v", n, err)
+	}
+	if n, err := repo.PurgeDailySummaries(ctx, day.AddDate(0, 0, 1), 10); err != nil || n != 0 {
+		t.Errorf("Expected no daily summaries after failed update, purged %d, %v", n, err)
+	}
+
+	if err := repo.ReplaceCityForecasts(ctx, 4242, forecasts, nil, nil); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for unknown city, got %v", err)
+	}
+}
+
+func testForecastByDateNotFound(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	createForecast(t, repo, dayForecast(time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC), 20), city.ID)
+
+	for _, tc := range []struct {
+		cityID int
+		date   string
+		want   error
+	}{
+		{city.ID, "2024-07-12", database.ErrForecastNotFound},
+		{4242, "2024-07-11", database.ErrCityNotFound},
+	} {
+		if _, err := repo.GetForecastByCityIDandDate(ctx, tc.cityID, tc.date); !errors.Is(err, tc.want) {
+			t.Errorf("GetForecastByCityIDandDate(%d, %s): expected %v, got %v", tc.cityID, tc.date, tc.want, err)
+		}
+		if _, err := repo.GetForecastByCityIDandDateTime(ctx, tc.cityID, tc.date, "12:00:00"); !errors.Is(err, tc.want) {
+			t.Errorf("GetForecastByCityIDandDateTime(%d, %s): expected %v, got %v", tc.cityID, tc.date, tc.want, err)
+		}
+	}
+}
+
+func testForecastByDateInvalidDate(t *testing.T, repo database.Repository) {
+	city := createCity(t, repo, "Berlin", "DE")
+	if _, err := repo.GetForecastByCityIDandDate(context.Background(), city.ID, "2024-13-45"); !errors.Is(err, database.ErrInvalidDate) {
+		t.Errorf("Expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func testForecastByDateTimeMatchesClock(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	createForecast(t, repo, dayForecast(time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC), 20), city.ID)
+
+	slot, err := repo.GetForecastByCityIDandDateTime(ctx, city.ID, "2024-07-11", "15:00:00")
+	if err != nil {
+		t.Fatalf("GetForecastByCityIDandDateTime: %v", err)
+	}
+	if slot.DtTxt != "2024-07-11 15:00:00" || slot.Main.Temp != 23 {
+		t.Errorf("Expected 15:00 slot, got %+v", slot)
+	}
+
+	for _, tc := range []struct {
+		clock string
+		want  error
+	}{
+		{"15:00", database.ErrInvalidDate},
+		{"3pm", database.ErrInvalidDate},
+		{"13:00:00", database.ErrForecastNotFound},
+	} {
+		if _, err := repo.GetForecastByCityIDandDateTime(ctx, city.ID, "2024-07-11", tc.clock); !errors.Is(err, tc.want) {
+			t.Errorf("Expected %v for time %q, got %v", tc.want, tc.clock, err)
+		}
+	}
+}
+
+func testForecastRangeCrossesDays(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	other := createCity(t, repo, "Paris", "FR")
+	day := time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		createForecast(t, repo, dayForecast(day.AddDate(0, 0, i), 20+float64(i)), city.ID)
+	}
+	createForecast(t, repo, dayForecast(day, 30), other.ID)
+
+	// bounds are inclusive, timezone of the bounds does not matter
+	berlin := time.FixedZone("CEST", 2*60*60)
+	slots, err := repo.GetForecastRange(ctx, city.ID, day.Add(12*time.Hour).In(berlin), day.Add(33*time.Hour).In(berlin))
+	if err != nil {
+		t.Fatalf("GetForecastRange: %v", err)
+	}
+	var got []string
+	for _, slot := range slots {
+		got = append(got, slot.DtTxt)
+	}
+	want := []string{"2024-07-11 12:00:00", "2024-07-11 15:00:00", "2024-07-12 09:00:00"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected slots %v, got %v", want, got)
+	}
+	if len(slots) > 0 && slots[2].Main.Temp != 18 {
+		t.Errorf("Expected 09:00 slot of the second day with temp 18, got %+v", slots[2].Main)
+	}
+
+	slots, err = repo.GetForecastRange(ctx, city.ID, day.AddDate(0, 0, 5), day.AddDate(0, 0, 6))
+	if err != nil || len(slots) != 0 {
+		t.Errorf("Expected no slots after the forecast, got %v, %v", slots, err)
+	}
+	if _, err := repo.GetForecastRange(ctx, 4242, day, day.AddDate(0, 0, 1)); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound, got %v", err)
+	}
+}
+
+func testShortForecast(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	forecasts := []models.WeatherInfo{dayForecast(today, 21)}
+
+	summaries := []models.DailySummary{
+		daySummary(today.AddDate(0, 0, -1), 10),
+		daySummary(today, 20),
+		daySummary(today.AddDate(0, 0, 1), 23),
+		daySummary(today.AddDate(0, 0, 2), 30),
+	}
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, nil, summaries); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+	// the next run no longer has the last day, past days are kept
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, nil, summaries[1:3]); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+
+	short, err := repo.GetShortForecastByCityID(ctx, city.ID)
+	if err != nil {
+		t.Fatalf("GetShortForecastByCityID: %v", err)
+	}
+	if short.City != "Berlin" || short.Country != "DE" {
+		t.Errorf("Expected Berlin, DE, got %s, %s", short.City, short.Country)
+	}
+	if short.AvgTemp != 21.5 || len(short.DateList) != 2 || !short.DateList[0].Equal(today) {
+		t.Errorf("Expected today and tomorrow with 21.5 degrees, got %+v", short)
+	}
+
+	if n, err := repo.PurgeDailySummaries(ctx, today, 10); err != nil || n != 1 {
+		t.Errorf("Expected yesterday summary purged, got %d, %v", n, err)
+	}
+}
+
+func testShortForecastNotFound(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+
+	if _, err := repo.GetShortForecastByCityID(ctx, city.ID); !errors.Is(err, database.ErrForecastNotFound) {
+		t.Errorf("Expected ErrForecastNotFound for city without forecasts, got %v", err)
+	}
+	if _, err := repo.GetShortForecastByCityID(ctx, 4242); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for unknown city, got %v", err)
+	}
+}
+
+func testDailySummaries(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	other := createCity(t, repo, "Paris", "FR")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	if _, err := repo.GetDailySummaries(ctx, city.ID); !errors.Is(err, database.ErrForecastNotFound) {
+		t.Errorf("Expected ErrForecastNotFound for city without summaries, got %v", err)
+	}
+	if _, err := repo.GetDailySummaries(ctx, 4242); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for unknown city, got %v", err)
+	}
+
+	tomorrow := daySummary(today.AddDate(0, 0, 1), 23)
+	tomorrow.Precipitation = 1.5
+	tomorrow.WindMax = 7.2
+	tomorrow.GustMax = 12.4
+	tomorrow.Condition = "Rain"
+	tomorrow.Icon = "10d"
+	summaries := []models.DailySummary{daySummary(today.AddDate(0, 0, -1), 10), daySummary(today, 20), tomorrow}
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, []models.WeatherInfo{dayForecast(today, 20)}, nil, summaries); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+	if err := repo.ReplaceCityForecasts(ctx, other.ID, []models.WeatherInfo{dayForecast(today, 30)}, nil, []models.DailySummary{daySummary(today, 30)}); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+
+	got, err := repo.GetDailySummaries(ctx, city.ID)
+	if err != nil {
+		t.Fatalf("GetDailySummaries: %v", err)
+	}
+	if len(got) != 2 || !got[0].Date.Equal(today) || got[0].TempMidday != 20 {
+		t.Fatalf("Expected today and tomorrow of the city, got %+v", got)
+	}
+	tomorrow.CityID = city.ID
+	if got[1] != tomorrow {
+		t.Errorf("Expected summary to round trip\nwant %+v\ngot  %+v", tomorrow, got[1])
+	}
+}
+
+func testPartialSummaryKeepsWholeDay(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	forecasts := []models.WeatherInfo{dayForecast(today, 20)}
+
+	partial := func(day time.Time, temp float64) models.DailySummary {
+		summary := daySummary(day, temp)
+		summary.Partial = true
+		return summary
+	}
+	first := []models.DailySummary{daySummary(today, 20), partial(today.AddDate(0, 0, 1), 23)}
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, nil, first); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+	// a later run covers the rest of today only and more of tomorrow
+	second := []models.DailySummary{partial(today, 25), partial(today.AddDate(0, 0, 1), 24)}
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, nil, second); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+
+	got, err := repo.GetDailySummaries(ctx, city.ID)
+	if err != nil {
+		t.Fatalf("GetDailySummaries: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected today and tomorrow, got %+v", got)
+	}
+	if got[0].TempMidday != 20 || got[0].Partial {
+		t.Errorf("Expected whole today to be kept, got %+v", got[0])
+	}
+	if got[1].TempMidday != 24 || !got[1].Partial {
+		t.Errorf("Expected partial tomorrow to be replaced, got %+v", got[1])
+	}
+}
+
+func testCitiesBatchQueries(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	berlin := createCity(t, repo, "Berlin", "DE")
+	paris := createCity(t, repo, "Paris", "FR")
+	rome := createCity(t, repo, "Rome", "IT")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	for i, city := range []models.City{berlin, paris} {
+		forecasts := []models.WeatherInfo{dayForecast(today, 20+float64(i)), dayForecast(today.AddDate(0, 0, 1), 25)}
+		summaries := []models.DailySummary{daySummary(today, 20+float64(i)), daySummary(today.AddDate(0, 0, 1), 25)}
+		if err := repo.ReplaceCityForecasts(ctx, city.ID, forecasts, nil, summaries); err != nil {
+			t.Fatalf("ReplaceCityForecasts: %v", err)
+		}
+	}
+
+	summaries, err := repo.GetCitiesDailySummaries(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetCitiesDailySummaries: %v", err)
+	}
+	if len(summaries) != 2 || len(summaries[berlin.ID]) != 2 || summaries[paris.ID][0].TempMidday != 21 || !summaries[paris.ID][1].Date.After(summaries[paris.ID][0].Date) {
+		t.Errorf("Expected two days of Berlin and Paris, got %+v", summaries)
+	}
+	summaries, err = repo.GetCitiesDailySummaries(ctx, []int{paris.ID, rome.ID, 4242})
+	if err != nil || len(summaries) != 1 || len(summaries[paris.ID]) != 2 {
+		t.Errorf("Expected two days of Paris only, got %+v, %v", summaries, err)
+	}
+
+	slots, err := repo.GetCitiesForecastRange(ctx, []int{berlin.ID, paris.ID}, today.Add(12*time.Hour), today.Add(33*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCitiesForecastRange: %v", err)
+	}
+	for _, city := range []models.City{berlin, paris} {
+		if len(slots[city.ID]) != 3 || !slots[city.ID][2].DtTime.Equal(today.Add(33*time.Hour)) {
+			t.Errorf("Expected 12:00, 15:00 and next 09:00 slots of %s, got %+v", city.Name, slots[city.ID])
+		}
+	}
+	slots, err = repo.GetCitiesForecastRange(ctx, nil, today.Add(15*time.Hour), today.Add(15*time.Hour))
+	if err != nil || len(slots) != 2 || len(slots[berlin.ID]) != 1 || slots[berlin.ID][0].Main.Temp != 23 {
+		t.Errorf("Expected 15:00 slot of every city, got %+v, %v", slots, err)
+	}
+	if slots, err := repo.GetCitiesForecastRange(ctx, []int{}, today, today.AddDate(0, 0, 2)); err != nil || len(slots) != 0 {
+		t.Errorf("Expected no slots for empty city list, got %+v, %v", slots, err)
+	}
+}
+
+func testPurgeForecasts(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	for day := 1; day <= 3; day++ {
+		createForecast(t, repo, dayForecast(time.Date(2024, 7, day, 0, 0, 0, 0, time.UTC), 20), city.ID)
+	}
+	before := time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC)
+
+	n, err := repo.PurgeForecastSlots(ctx, before, 1)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 forecast cleared by limited batch, got %d, %v", n, err)
+	}
+	n, err = repo.PurgeForecastSlots(ctx, before, 10)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected remaining old forecast cleared, got %d, %v", n, err)
+	}
+	forecasts, err := repo.GetForecastByCityIDandDate(ctx, city.ID, "2024-07-01")
+	if err != nil || len(forecasts) != 1 || len(forecasts[0].AdditionalInfo) != 0 || forecasts[0].Temp != 20 {
+		t.Errorf("Expected old forecast to keep temp without slots, got %+v, %v", forecasts, err)
+	}
+
+	n, err = repo.PurgeForecasts(ctx, before, 10)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 old forecasts deleted, got %d, %v", n, err)
+	}
+	forecasts, err = repo.GetForecastByCityIDandDate(ctx, city.ID, "2024-07-03")
+	if err != nil || len(forecasts) != 1 || len(forecasts[0].AdditionalInfo) != 3 {
+		t.Errorf("Expected new forecast to stay untouched, got %+v, %v", forecasts, err)
+	}
+}
+
+func testObservations(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+	at := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	humidity := 55
+
+	observations := []models.Observation{
+		{CityID: city.ID, ObservedAt: at, Source: models.SourceSensor, Temp: 20, Humidity: &humidity, Condition: "Clear"},
+		{CityID: city.ID, ObservedAt: at, Source: models.SourceOpenWeatherMap, Temp: 21},
+		{CityID: city.ID, ObservedAt: at.Add(-time.Hour), Source: models.SourceSensor, Temp: 19},
+		{CityID: city.ID, ObservedAt: at.Add(time.Hour), Source: models.SourceSensor, Temp: 22},
+	}
+	for i := range observations {
+		if err := repo.CreateObservation(ctx, &observations[i]); err != nil {
+			t.Fatalf("CreateObservation: %v", err)
+		}
+		if observations[i].ID == 0 {
+			t.Errorf("Expected observation to get id")
+		}
+	}
+
+	// repeated reading from the same source replaces the old one
+	repeated := models.Observation{CityID: city.ID, ObservedAt: at, Source: models.SourceSensor, Temp: 20.5}
+	if err := repo.CreateObservation(ctx, &repeated); err != nil {
+		t.Fatalf("CreateObservation duplicate: %v", err)
+	}
+	if repeated.ID != observations[0].ID {
+		t.Errorf("Expected duplicate observation to keep id %d, got %d", observations[0].ID, repeated.ID)
+	}
+
+	got, err := repo.GetObservations(ctx, city.ID, at.Add(-time.Hour), at.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetObservations: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 observations in [from, to), got %d", len(got))
+	}
+	if !got[0].ObservedAt.Equal(at.Add(-time.Hour)) || got[1].Source != models.SourceOpenWeatherMap || got[2].Source != models.SourceSensor {
+		t.Errorf("Expected observations ordered by time and source, got %+v", got)
+	}
+	if got[2].Temp != 20.5 || got[2].Humidity != nil || got[2].Condition != "" {
+		t.Errorf("Expected repeated reading to replace all values, got %+v", got[2])
+	}
+
+	empty, err := repo.GetObservations(ctx, city.ID, at.Add(2*time.Hour), at.Add(3*time.Hour))
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("Expected empty non-nil list for period without observations, got %#v, %v", empty, err)
+	}
+	if _, err := repo.GetObservations(ctx, 4242, at.Add(-time.Hour), at.Add(time.Hour)); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for unknown city, got %v", err)
+	}
+
+	unknown := models.Observation{CityID: 4242, ObservedAt: at, Source: models.SourceSensor, Temp: 20}
+	if err := repo.CreateObservation(ctx, &unknown); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for observation of unknown city, got %v", err)
+	}
+}
+
+func testVerificationPairs(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	berlin := createCity(t, repo, "Berlin", "DE")
+	paris := createCity(t, repo, "Paris", "FR")
+	valid := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	wet := 0.4
+
+	for _, o := range []models.Observation{
+		{CityID: berlin.ID, ObservedAt: valid.Add(-40 * time.Minute), Source: models.SourceSensor, Temp: 18},
+		{CityID: berlin.ID, ObservedAt: valid.Add(10 * time.Minute), Source: models.SourceSensor, Temp: 20, Precipitation: &wet},
+		{CityID: paris.ID, ObservedAt: valid.Add(3 * time.Hour).Add(90 * time.Minute), Source: models.SourceSensor, Temp: 30},
+	} {
+		o := o
+		if err := repo.CreateObservation(ctx, &o); err != nil {
+			t.Fatalf("CreateObservation: %v", err)
+		}
+	}
+
+	slots := []models.ForecastSlot{
+		{CityID: berlin.ID, Provider: "a", IssuedAt: valid.Add(-24 * time.Hour), ValidAt: valid, Temp: 22, Pop: 0.7},
+		{CityID: berlin.ID, Provider: "b", IssuedAt: valid.Add(-24 * time.Hour), ValidAt: valid, Temp: 21},
+		{CityID: paris.ID, Provider: "a", IssuedAt: valid.Add(-24 * time.Hour), ValidAt: valid.Add(3 * time.Hour), Temp: 25},
+	}
+	if err := repo.CreateForecastSlots(ctx, slots); err != nil {
+		t.Fatalf("CreateForecastSlots: %v", err)
+	}
+	// repeated slot of the same run replaces the old one
+	if err := repo.CreateForecastSlots(ctx, slots[:1]); err != nil {
+		t.Fatalf("CreateForecastSlots duplicate: %v", err)
+	}
+
+	pairs, err := repo.GetVerificationPairs(ctx, database.VerificationFilter{From: valid.Add(-time.Hour), To: valid.Add(6 * time.Hour)})
+	if err != nil {
+		t.Fatalf("GetVerificationPairs: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("Expected 2 pairs, observation more than an hour away must be skipped, got %+v", pairs)
+	}
+	p := pairs[0]
+	if p.CityID != berlin.ID || p.Provider != "a" || p.ForecastTemp != 22 || p.ForecastPop != 0.7 {
+		t.Errorf("Expected forecast of provider a for Berlin, got %+v", p)
+	}
+	if p.ObservedTemp != 20 || !p.ObservedAt.Equal(valid.Add(10*time.Minute)) || p.ObservedPrecipitation == nil || *p.ObservedPrecipitation != wet {
+		t.Errorf("Expected nearest observation, got %+v", p)
+	}
+	if p.Lead() != 24*time.Hour {
+		t.Errorf("Expected 24h lead, got %v", p.Lead())
+	}
+
+	pairs, err = repo.GetVerificationPairs(ctx, database.VerificationFilter{CityID: berlin.ID, Provider: "b", From: valid, To: valid.Add(time.Hour)})
+	if err != nil || len(pairs) != 1 || pairs[0].Provider != "b" {
+		t.Errorf("Expected only pair of provider b, got %+v, %v", pairs, err)
+	}
+
+	pairs, err = repo.GetVerificationPairs(ctx, database.VerificationFilter{From: valid.Add(time.Hour), To: valid.Add(2 * time.Hour)})
+	if err != nil || pairs == nil || len(pairs) != 0 {
+		t.Errorf("Expected empty non-nil list, got %#v, %v", pairs, err)
+	}
+
+	if err := repo.CreateForecastSlots(ctx, []models.ForecastSlot{{CityID: 4242, Provider: "a", IssuedAt: valid, ValidAt: valid}}); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for slot of unknown city, got %v", err)
+	}
+	if _, err := repo.GetVerificationPairs(ctx, database.VerificationFilter{CityID: 4242, From: valid, To: valid.Add(time.Hour)}); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound for pairs of unknown city, got %v", err)
+	}
+
+	n, err := repo.PurgeForecastHistory(ctx, valid.Add(time.Hour), 10)
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 issued slots purged, got %d, %v", n, err)
+	}
+}
+
+func testBiasCorrectionVersions(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	city := createCity(t, repo, "Berlin", "DE")
+
+	latest, err := repo.GetBiasCorrection(ctx, 0)
+	if err != nil || latest != nil {
+		t.Fatalf("Expected no correction, got %+v, %v", latest, err)
+	}
+
+	trainedAt := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	versions := make([]int, 0)
+	for i := 1; i <= 2; i++ {
+		correction := models.BiasCorrection{
+			TrainedAt:  trainedAt,
+			From:       trainedAt.AddDate(0, 0, -30),
+			To:         trainedAt,
+			MinSamples: 10,
+			Offsets:    []models.BiasOffset{{CityID: city.ID, Hour: 12, LeadHours: 24, Bias: float64(i), Samples: 12}},
+		}
+		if err := repo.CreateBiasCorrection(ctx, &correction); err != nil {
+			t.Fatalf("CreateBiasCorrection: %v", err)
+		}
+		versions = append(versions, correction.Version)
+	}
+	if versions[0] == 0 || versions[1] <= versions[0] {
+		t.Fatalf("Expected increasing versions, got %v", versions)
+	}
+
+	first, err := repo.GetBiasCorrection(ctx, versions[0])
+	if err != nil || first == nil {
+		t.Fatalf("GetBiasCorrection(%d): %+v, %v", versions[0], first, err)
+	}
+	if first.Version != versions[0] || first.MinSamples != 10 || !first.From.Equal(trainedAt.AddDate(0, 0, -30)) {
+		t.Errorf("Expected stored training parameters, got %+v", first)
+	}
+	if len(first.Offsets) != 1 || math.Abs(first.Offsets[0].Bias-1) > 1e-9 || first.Offsets[0].Samples != 12 {
+		t.Errorf("Expected stored offsets, got %+v", first.Offsets)
+	}
+
+	latest, err = repo.GetBiasCorrection(ctx, 0)
+	if err != nil || latest == nil || latest.Version != versions[1] {
+		t.Errorf("Expected latest version %d, got %+v, %v", versions[1], latest, err)
+	}
+
+	missing, err := repo.GetBiasCorrection(ctx, versions[1]+100)
+	if err != nil || missing != nil {
+		t.Errorf("Expected nil for unknown version, got %+v, %v", missing, err)
+	}
+}
@@ -0,0 +1,13 @@
+package database
+
+import "errors"
+
+// Errors returned by every Repository implementation, callers should check them with errors.Is
+var (
+	// ErrCityNotFound - city with requested id does not exist
+	ErrCityNotFound = errors.New("city not found")
+	// ErrForecastNotFound - city exists, but there is no forecast for requested date or time
+	ErrForecastNotFound = errors.New("no forecast found")
+	// ErrInvalidDate - requested date or time is not a valid calendar date or clock time
+	ErrInvalidDate = errors.New("invalid date")
+)
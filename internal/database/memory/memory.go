@@ -0,0 +1,697 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+)
+
+// forecastKey identifies a forecast by city and date like unique_city_date constraint
+type forecastKey struct {
+	cityID int
+	date   string
+}
+
+// observationKey identifies an observation like unique_city_observed_at_source constraint
+type observationKey struct {
+	cityID     int
+	observedAt int64
+	source     string
+}
+
+// slotKey identifies an issued forecast slot like unique_city_provider_issued_valid constraint
+type slotKey struct {
+	cityID   int
+	provider string
+	issuedAt int64
+	validAt  int64
+}
+
+// forecastRow is a stored forecast, additional info is kept as JSON like in JSONB column
+type forecastRow struct {
+	id             int
+	temp           float64
+	date           time.Time
+	additionalInfo []byte
+	cityID         int
+}
+
+// MemoryRepository implements Repository in memory, it is safe for concurrent use
+type MemoryRepository struct {
+	mu sync.RWMutex
+
+	cities       map[int]models.City
+	forecasts    map[forecastKey]*forecastRow
+	summaries    map[forecastKey]models.DailySummary
+	observations map[observationKey]models.Observation
+	slots        map[slotKey]models.ForecastSlot
+	corrections  []models.BiasCorrection
+
+	cityID        int
+	forecastID    int
+	observationID int
+}
+
+// NewMemoryRepository creates a new empty MemoryRepository returns interface
+func NewMemoryRepository() database.Repository {
+	return &MemoryRepository{
+		cities:       make(map[int]models.City),
+		forecasts:    make(map[forecastKey]*forecastRow),
+		summaries:    make(map[forecastKey]models.DailySummary),
+		observations: make(map[observationKey]models.Observation),
+		slots:        make(map[slotKey]models.ForecastSlot),
+	}
+}
+
+// dateOf returns midnight UTC of the calendar date of t like DATE column
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// checkCity returns database.ErrCityNotFound if city does not exist, must be called with lock held
+func (r *MemoryRepository) checkCity(cityID int) error {
+	if _, ok := r.cities[cityID]; !ok {
+		return database.ErrCityNotFound
+	}
+	return nil
+}
+
+// CreateCity creates a new city in memory or refreshes coordinates of an existing one
+func (r *MemoryRepository) CreateCity(ctx context.Context, city *models.City) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.cities {
+		if c.Name == city.Name && c.Country == city.Country {
+			city.ID = id
+			r.cities[id] = *city
+			return nil
+		}
+	}
+
+	r.cityID++
+	city.ID = r.cityID
+	r.cities[city.ID] = *city
+	return nil
+}
+
+// GetAllCities returns all cities sorted by city name
+func (r *MemoryRepository) GetAllCities(ctx context.Context) ([]models.City, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cities := make([]models.City, 0, len(r.cities))
+	for _, city := range r.cities {
+		cities = append(cities, city)
+	}
+
+	// sort cities by city name, then by id as databases do
+	sort.Slice(cities, func(i, j int) bool {
+		return database.CityFilter{}.Less(cities[i], cities[j])
+	})
+
+	return cities, nil
+}
+
+// ListCities returns a page of cities selected by filter, after the cursor in sort order
+func (r *MemoryRepository) ListCities(ctx context.Context, filter database.CityFilter) ([]models.City, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var after models.City
+	if filter.After != nil {
+		after = models.City{ID: filter.After.ID, Name: filter.After.Name, Country: filter.After.Country, Latitude: filter.After.Latitude}
+	}
+
+	cities := make([]models.City, 0)
+	for _, city := range r.cities {
+		if filter.Country != "" && city.Country != filter.Country {
+			continue
+		}
+		if filter.After != nil && !filter.Less(after, city) {
+			continue
+		}
+		cities = append(cities, city)
+	}
+	sort.Slice(cities, func(i, j int) bool {
+		return filter.Less(cities[i], cities[j])
+	})
+	if filter.Limit > 0 && len(cities) > filter.Limit {
+		cities = cities[:filter.Limit]
+	}
+	return cities, nil
+}
+
+// CreateForecast creates a new forecast for concrete city or replaces the forecast for the same date
+func (r *MemoryRepository) CreateForecast(ctx context.Context, forecast *models.WeatherInfo, cityID int) error {
+	additionalInfo, err := json.Marshal(forecast.AdditionalInfo)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkCity(cityID); err != nil {
+		return err
+	}
+	r.putForecast(cityID, forecast, additionalInfo)
+	return nil
+}
+
+// putForecast inserts or replaces forecast for the same city and date, must be called with lock held
+func (r *MemoryRepository) putForecast(cityID int, forecast *models.WeatherInfo, additionalInfo []byte) {
+	key := forecastKey{cityID: cityID, date: forecast.Date.Format("2006-01-02")}
+	row, ok := r.forecasts[key]
+	if !ok {
+		r.forecastID++
+		row = &forecastRow{id: r.forecastID, date: dateOf(forecast.Date), cityID: cityID}
+		r.forecasts[key] = row
+	}
+	// temp column is INT, fractional part is dropped
+	row.temp = math.Trunc(forecast.Temp)
+	row.additionalInfo = additionalInfo
+}
+
+// ReplaceCityForecasts saves forecasts of one city, issued slots and daily summaries, days from the earliest new date on
+// that are missing in forecasts or summaries are deleted. Nothing is changed on error
+func (r *MemoryRepository) ReplaceCityForecasts(ctx context.Context, cityID int, forecasts []models.WeatherInfo, slots []models.ForecastSlot, summaries []models.DailySummary) error {
+	if len(forecasts) == 0 {
+		return nil
+	}
+
+	additionalInfo := make([][]byte, len(forecasts))
+	dates := make(map[string]bool, len(forecasts))
+	from := dateOf(forecasts[0].Date)
+	for i := range forecasts {
+		var err error
+		if additionalInfo[i], err = json.Marshal(forecasts[i].AdditionalInfo); err != nil {
+			return err
+		}
+		dates[forecasts[i].Date.Format("2006-01-02")] = true
+		if day := dateOf(forecasts[i].Date); day.Before(from) {
+			from = day
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// check everything first, so nothing is saved on error like in a transaction
+	if err := r.checkCity(cityID); err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if err := r.checkCity(slot.CityID); err != nil {
+			return err
+		}
+	}
+
+	for key, row := range r.forecasts {
+		if key.cityID == cityID && !row.date.Before(from) && !dates[key.date] {
+			delete(r.forecasts, key)
+		}
+	}
+	for i := range forecasts {
+		r.putForecast(cityID, &forecasts[i], additionalInfo[i])
+	}
+	for _, slot := range slots {
+		key := slotKey{cityID: slot.CityID, provider: slot.Provider, issuedAt: slot.IssuedAt.UnixNano(), validAt: slot.ValidAt.UnixNano()}
+		r.slots[key] = slot
+	}
+	r.replaceSummaries(cityID, summaries)
+	return nil
+}
+
+// replaceSummaries saves daily summaries ordered by date and deletes summaries of the city from the first date on
+// that are missing in summaries, a partial summary keeps the stored summary of the whole day. Must be called with lock held
+func (r *MemoryRepository) replaceSummaries(cityID int, summaries []models.DailySummary) {
+	if len(summaries) == 0 {
+		return
+	}
+
+	from := dateOf(summaries[0].Date)
+	dates := make(map[string]bool, len(summaries))
+	for _, summary := range summaries {
+		dates[summary.Date.Format("2006-01-02")] = true
+	}
+	for key, summary := range r.summaries {
+		if key.cityID == cityID && !summary.Date.Before(from) && !dates[key.date] {
+			delete(r.summaries, key)
+		}
+	}
+	for _, summary := range summaries {
+		summary.CityID = cityID
+		summary.Date = dateOf(summary.Date)
+		key := forecastKey{cityID: cityID, date: summary.Date.Format("2006-01-02")}
+		if stored, ok := r.summaries[key]; ok && summary.Partial && !stored.Partial {
+			continue
+		}
+		r.summaries[key] = summary
+	}
+}
+
+// GetShortForecastByCityID returns short forecast for concrete city from daily summaries of local today and later days,
+// average temperature is the mean of midday temperatures
+func (r *MemoryRepository) GetShortForecastByCityID(ctx context.Context, cityID int) (*models.ShortForecast, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.checkCity(cityID); err != nil {
+		return nil, err
+	}
+
+	summaries := r.upcomingSummaries(cityID)
+	if len(summaries) == 0 {
+		return nil, database.ErrForecastNotFound
+	}
+
+	var sumTemp float64
+	dateSlice := make([]time.Time, 0, len(summaries))
+	for _, summary := range summaries {
+		sumTemp += summary.TempMidday
+		dateSlice = append(dateSlice, summary.Date)
+	}
+
+	return &models.ShortForecast{
+		City:     r.cities[cityID].Name,
+		Country:  r.cities[cityID].Country,
+		AvgTemp:  sumTemp / float64(len(summaries)),
+		DateList: dateSlice,
+	}, nil
+}
+
+// GetDailySummaries returns daily summaries of concrete city from its local today on
+func (r *MemoryRepository) GetDailySummaries(ctx context.Context, cityID int) ([]models.DailySummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.checkCity(cityID); err != nil {
+		return nil, err
+	}
+	summaries := r.upcomingSummaries(cityID)
+	if len(summaries) == 0 {
+		return nil, database.ErrForecastNotFound
+	}
+	return summaries, nil
+}
+
+// GetCitiesDailySummaries returns daily summaries of cities from their local today on grouped by city
+func (r *MemoryRepository) GetCitiesDailySummaries(ctx context.Context, cityIDs []int) (map[int][]models.DailySummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summaries := make(map[int][]models.DailySummary)
+	for _, cityID := range r.selectCities(cityIDs) {
+		if upcoming := r.upcomingSummaries(cityID); len(upcoming) > 0 {
+			summaries[cityID] = upcoming
+		}
+	}
+	return summaries, nil
+}
+
+// selectCities returns existing cities among cityIDs, nil cityIDs selects all cities, must be called with lock held
+func (r *MemoryRepository) selectCities(cityIDs []int) []int {
+	selected := make([]int, 0, len(r.cities))
+	if cityIDs == nil {
+		for cityID := range r.cities {
+			selected = append(selected, cityID)
+		}
+		return selected
+	}
+	for _, cityID := range cityIDs {
+		if _, ok := r.cities[cityID]; ok {
+			selected = append(selected, cityID)
+		}
+	}
+	return selected
+}
+
+// upcomingSummaries returns summaries of the city from its local today on ordered by date, must be called with lock held
+func (r *MemoryRepository) upcomingSummaries(cityID int) []models.DailySummary {
+	now := time.Now().UTC()
+	summaries := make([]models.DailySummary, 0)
+	for key, summary := range r.summaries {
+		today := dateOf(now.Add(time.Duration(summary.UTCOffset) * time.Second))
+		if key.cityID == cityID && !summary.Date.Before(today) {
+			summaries = append(summaries, summary)
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Date.Before(summaries[j].Date)
+	})
+	return summaries
+}
+
+// GetForecastByCityIDandDate returns forecasts for concrete date
+func (r *MemoryRepository) GetForecastByCityIDandDate(ctx context.Context, cityID int, date string) ([]models.WeatherInfo, error) {
+	day, err := database.ParseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.checkCity(cityID); err != nil {
+		return nil, err
+	}
+	row, ok := r.forecasts[forecastKey{cityID: cityID, date: day.Format("2006-01-02")}]
+	if !ok {
+		return nil, database.ErrForecastNotFound
+	}
+
+	forecast := models.WeatherInfo{
+		ID:     row.id,
+		Temp:   row.temp,
+		Date:   row.date,
+		CityID: row.cityID,
+	}
+	if err := json.Unmarshal(row.additionalInfo, &forecast.AdditionalInfo); err != nil {
+		return nil, err
+	}
+	return []models.WeatherInfo{forecast}, nil
+}
+
+// GetForecastByCityIDandDateTime returns forecast for concrete date and time
+func (r *MemoryRepository) GetForecastByCityIDandDateTime(ctx context.Context, cityID int, date, time string) (*models.List, error) {
+	if err := database.ValidateClock(time); err != nil {
+		return nil, err
+	}
+	forecasts, err := r.GetForecastByCityIDandDate(ctx, cityID, date)
+	if err != nil {
+		return nil, err
+	}
+	// search for forecast for concrete time for concrete date
+	for _, forecast := range forecasts {
+		for _, info := range forecast.AdditionalInfo {
+			if info.DtTime.Format("15:04:05") == time {
+				return &info, nil
+			}
+		}
+	}
+	return nil, database.ErrForecastNotFound
+}
+
+// GetForecastRange returns 3-hour slots of concrete city valid in [from, to] ordered by time
+func (r *MemoryRepository) GetForecastRange(ctx context.Context, cityID int, from, to time.Time) ([]models.List, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.checkCity(cityID); err != nil {
+		return nil, err
+	}
+	return r.forecastRange(cityID, from, to)
+}
+
+// GetCitiesForecastRange returns 3-hour slots of cities valid in [from, to] grouped by city
+func (r *MemoryRepository) GetCitiesForecastRange(ctx context.Context, cityIDs []int, from, to time.Time) (map[int][]models.List, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	slots := make(map[int][]models.List)
+	for _, cityID := range r.selectCities(cityIDs) {
+		citySlots, err := r.forecastRange(cityID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(citySlots) > 0 {
+			slots[cityID] = citySlots
+		}
+	}
+	return slots, nil
+}
+
+// forecastRange returns slots of the city valid in [from, to] ordered by time, must be called with lock held
+func (r *MemoryRepository) forecastRange(cityID int, from, to time.Time) ([]models.List, error) {
+	slots := make([]models.List, 0)
+	for _, row := range r.forecasts {
+		if row.cityID != cityID || row.date.Before(dateOf(from.UTC())) || row.date.After(to) {
+			continue
+		}
+		var info []models.List
+		if err := json.Unmarshal(row.additionalInfo, &info); err != nil {
+			return nil, err
+		}
+		for _, l := range info {
+			if !l.DtTime.Before(from) && !l.DtTime.After(to) {
+				slots = append(slots, l)
+			}
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].DtTime.Before(slots[j].DtTime)
+	})
+	return slots, nil
+}
+
+// PurgeForecastSlots drops 3-hour slots from up to limit forecasts older than before, keeping the daily temperature
+func (r *MemoryRepository) PurgeForecastSlots(ctx context.Context, before time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for _, row := range r.forecasts {
+		if n == int64(limit) {
+			break
+		}
+		if row.date.Before(before) && string(row.additionalInfo) != "[]" {
+			row.additionalInfo = []byte("[]")
+			n++
+		}
+	}
+	return n, nil
+}
+
+// PurgeForecasts deletes up to limit forecasts older than before
+func (r *MemoryRepository) PurgeForecasts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, row := range r.forecasts {
+		if n == int64(limit) {
+			break
+		}
+		if row.date.Before(before) {
+			delete(r.forecasts, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// PurgeDailySummaries deletes up to limit daily summaries older than before
+func (r *MemoryRepository) PurgeDailySummaries(ctx context.Context, before time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, summary := range r.summaries {
+		if n == int64(limit) {
+			break
+		}
+		if summary.Date.Before(before) {
+			delete(r.summaries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CreateObservation saves measured weather for concrete city, a repeated reading from the same source replaces the old one
+func (r *MemoryRepository) CreateObservation(ctx context.Context, observation *models.Observation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkCity(observation.CityID); err != nil {
+		return err
+	}
+
+	key := observationKey{cityID: observation.CityID, observedAt: observation.ObservedAt.UnixNano(), source: observation.Source}
+	if old, ok := r.observations[key]; ok {
+		observation.ID = old.ID
+	} else {
+		r.observationID++
+		observation.ID = r.observationID
+	}
+	stored := *observation
+	stored.ObservedAt = stored.ObservedAt.UTC()
+	r.observations[key] = stored
+	return nil
+}
+
+// GetObservations returns observations for concrete city in [from, to) ordered by time
+func (r *MemoryRepository) GetObservations(ctx context.Context, cityID int, from, to time.Time) ([]models.Observation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.checkCity(cityID); err != nil {
+		return nil, err
+	}
+
+	observations := make([]models.Observation, 0)
+	for _, o := range r.observations {
+		if o.CityID == cityID && !o.ObservedAt.Before(from) && o.ObservedAt.Before(to) {
+			observations = append(observations, o)
+		}
+	}
+	sort.Slice(observations, func(i, j int) bool {
+		if !observations[i].ObservedAt.Equal(observations[j].ObservedAt) {
+			return observations[i].ObservedAt.Before(observations[j].ObservedAt)
+		}
+		return observations[i].Source < observations[j].Source
+	})
+	return observations, nil
+}
+
+// CreateForecastSlots saves issued forecast slots, a repeated slot of the same run replaces the old one
+func (r *MemoryRepository) CreateForecastSlots(ctx context.Context, slots []models.ForecastSlot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// check all slots first, so nothing is saved on error like in a transaction
+	for _, slot := range slots {
+		if err := r.checkCity(slot.CityID); err != nil {
+			return err
+		}
+	}
+	for _, slot := range slots {
+		key := slotKey{cityID: slot.CityID, provider: slot.Provider, issuedAt: slot.IssuedAt.UnixNano(), validAt: slot.ValidAt.UnixNano()}
+		r.slots[key] = slot
+	}
+	return nil
+}
+
+// PurgeForecastHistory deletes up to limit issued forecast slots valid before before
+func (r *MemoryRepository) PurgeForecastHistory(ctx context.Context, before time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, slot := range r.slots {
+		if n == int64(limit) {
+			break
+		}
+		if slot.ValidAt.Before(before) {
+			delete(r.slots, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// GetVerificationPairs matches every issued forecast slot valid in [from, to) with the nearest observation within an hour,
+// pairs are ordered by city, valid time, issue time and provider
+func (r *MemoryRepository) GetVerificationPairs(ctx context.Context, filter database.VerificationFilter) ([]models.VerificationPair, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if filter.CityID != 0 {
+		if err := r.checkCity(filter.CityID); err != nil {
+			return nil, err
+		}
+	}
+
+	pairs := make([]models.VerificationPair, 0)
+	for _, slot := range r.slots {
+		if (filter.CityID != 0 && slot.CityID != filter.CityID) || (filter.Provider != "" && slot.Provider != filter.Provider) {
+			continue
+		}
+		if slot.ValidAt.Before(filter.From) || !slot.ValidAt.Before(filter.To) {
+			continue
+		}
+
+		var nearest *models.Observation
+		var nearestDiff time.Duration
+		for _, o := range r.observations {
+			if o.CityID != slot.CityID {
+				continue
+			}
+			diff := o.ObservedAt.Sub(slot.ValidAt)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > time.Hour {
+				continue
+			}
+			if nearest == nil || diff < nearestDiff || (diff == nearestDiff && o.Source < nearest.Source) {
+				o := o
+				nearest = &o
+				nearestDiff = diff
+			}
+		}
+		if nearest == nil {
+			continue
+		}
+
+		pairs = append(pairs, models.VerificationPair{
+			CityID:                slot.CityID,
+			Provider:              slot.Provider,
+			IssuedAt:              slot.IssuedAt,
+			ValidAt:               slot.ValidAt,
+			ForecastTemp:          slot.Temp,
+			ForecastPop:           slot.Pop,
+			ObservedAt:            nearest.ObservedAt,
+			ObservedTemp:          nearest.Temp,
+			ObservedPrecipitation: nearest.Precipitation,
+			ObservedCondition:     nearest.Condition,
+		})
+	}
+
+	// sort pairs by city, valid time, issue time and provider
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].CityID != pairs[j].CityID {
+			return pairs[i].CityID < pairs[j].CityID
+		}
+		if !pairs[i].ValidAt.Equal(pairs[j].ValidAt) {
+			return pairs[i].ValidAt.Before(pairs[j].ValidAt)
+		}
+		if !pairs[i].IssuedAt.Equal(pairs[j].IssuedAt) {
+			return pairs[i].IssuedAt.Before(pairs[j].IssuedAt)
+		}
+		return pairs[i].Provider < pairs[j].Provider
+	})
+	return pairs, nil
+}
+
+// CreateBiasCorrection saves trained corrections as a new version and sets correction.Version
+func (r *MemoryRepository) CreateBiasCorrection(ctx context.Context, correction *models.BiasCorrection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, o := range correction.Offsets {
+		if err := r.checkCity(o.CityID); err != nil {
+			return err
+		}
+	}
+
+	correction.Version = len(r.corrections) + 1
+	stored := *correction
+	stored.Offsets = append([]models.BiasOffset(nil), correction.Offsets...)
+	r.corrections = append(r.corrections, stored)
+	return nil
+}
+
+// GetBiasCorrection returns corrections of concrete version, version 0 means the latest one.
+// Returns nil if there is no such version
+func (r *MemoryRepository) GetBiasCorrection(ctx context.Context, version int) (*models.BiasCorrection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if version == 0 {
+		version = len(r.corrections)
+	}
+	if version < 1 || version > len(r.corrections) {
+		return nil, nil
+	}
+
+	correction := r.corrections[version-1]
+	correction.Offsets = append(make([]models.BiasOffset, 0, len(correction.Offsets)), correction.Offsets...)
+	return &correction, nil
+}
@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"testing"
+	"weather_service/internal/database"
+	"weather_service/internal/database/databasetest"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	databasetest.TestRepository(t, func(t *testing.T) database.Repository {
+		return NewMemoryRepository()
+	})
+}
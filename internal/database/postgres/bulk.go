@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"weather_service/internal/database"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReplaceForecasts copies forecasts, issued slots and daily summaries of many cities into temporary staging tables
+// with COPY and merges them with a few statements in one transaction, so thousands of cities are saved in one
+// round trip. Every city is replaced like ReplaceCityForecasts does: days from its earliest new date on that are
+// missing are deleted, a partial summary keeps the stored summary of the whole day and listeners of
+// ForecastChangesChannel are notified about every changed date after commit. Cities without forecasts are skipped,
+// the last row wins when the same city and date repeats
+func (r *PostgresRepository) ReplaceForecasts(ctx context.Context, cities []database.CityForecasts) error {
+	qStaging := `
+		CREATE TEMP TABLE forecasts_staging (
+			ord INT NOT NULL,
+			temp DOUBLE PRECISION NOT NULL,
+			date DATE NOT NULL,
+			additional_info JSONB,
+			city_id INT NOT NULL
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE forecast_history_staging (
+			ord INT NOT NULL,
+			city_id INT NOT NULL,
+			provider CHARACTER VARYING NOT NULL,
+			issued_at TIMESTAMPTZ NOT NULL,
+			valid_at TIMESTAMPTZ NOT NULL,
+			temp DOUBLE PRECISION NOT NULL,
+			pop DOUBLE PRECISION NOT NULL,
+			precipitation DOUBLE PRECISION NOT NULL
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE daily_summaries_staging (
+			ord INT NOT NULL,
+			city_id INT NOT NULL,
+			date DATE NOT NULL,
+			utc_offset INT NOT NULL,
+			temp_min DOUBLE PRECISION NOT NULL,
+			temp_max DOUBLE PRECISION NOT NULL,
+			temp_mean DOUBLE PRECISION NOT NULL,
+			temp_midday DOUBLE PRECISION NOT NULL,
+			precipitation DOUBLE PRECISION NOT NULL,
+			wind_max DOUBLE PRECISION NOT NULL,
+			gust_max DOUBLE PRECISION NOT NULL,
+			pop_max DOUBLE PRECISION NOT NULL,
+			condition CHARACTER VARYING NOT NULL,
+			icon CHARACTER VARYING NOT NULL,
+			partial BOOLEAN NOT NULL
+		) ON COMMIT DROP
+	`
+	// temp is truncated like pgx does for INT parameter of ReplaceCityForecasts
+	qForecasts := `
+		INSERT INTO forecasts
+		(temp, date, additional_info, city_id)
+		SELECT DISTINCT ON (city_id, date) trunc(temp)::int, date, additional_info, city_id
+		FROM forecasts_staging
+		ORDER BY city_id, date, ord DESC
+
+		ON CONFLICT (city_id, date)
+		DO UPDATE SET temp = excluded.temp, additional_info = excluded.additional_info
+	`
+	// notifications are delivered only when transaction commits, one for every saved or deleted date
+	qStale := `
+		WITH stale AS (
+			DELETE FROM forecasts
+			USING (SELECT city_id, min(date) AS date FROM forecasts_staging GROUP BY city_id) AS earliest
+			WHERE forecasts.city_id = earliest.city_id
+			AND forecasts.date >= earliest.date
+			AND NOT EXISTS (
+				SELECT 1 FROM forecasts_staging
+				WHERE forecasts_staging.city_id = forecasts.city_id AND forecasts_staging.date = forecasts.date
+			)
+			RETURNING forecasts.city_id, forecasts.date
+		)
+		SELECT pg_notify('` + ForecastChangesChannel + `', json_build_object('city_id', city_id, 'date', date)::text)
+		FROM (SELECT city_id, date FROM stale UNION SELECT city_id, date FROM forecasts_staging) AS changed
+	`
+	qSlots := `
+		INSERT INTO forecast_history
+			(city_id, provider, issued_at, valid_at, temp, pop, precipitation)
+		SELECT DISTINCT ON (city_id, provider, issued_at, valid_at) city_id, provider, issued_at, valid_at, temp, pop, precipitation
+		FROM forecast_history_staging
+		ORDER BY city_id, provider, issued_at, valid_at, ord DESC
+
+		ON CONFLICT (city_id, provider, issued_at, valid_at)
+		DO UPDATE SET temp = excluded.temp, pop = excluded.pop, precipitation = excluded.precipitation
+	`
+	qSummaries := `
+		INSERT INTO daily_summaries
+			(city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday, precipitation, wind_max, gust_max, pop_max, condition, icon, partial)
+		SELECT DISTINCT ON (city_id, date) city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday,
+			precipitation, wind_max, gust_max, pop_max, condition, icon, partial
+		FROM daily_summaries_staging
+		ORDER BY city_id, date, ord DESC
+
+		ON CONFLICT (city_id, date)
+		DO UPDATE SET utc_offset = excluded.utc_offset, temp_min = excluded.temp_min, temp_max = excluded.temp_max,
+			temp_mean = excluded.temp_mean, temp_midday = excluded.temp_midday, precipitation = excluded.precipitation,
+			wind_max = excluded.wind_max, gust_max = excluded.gust_max, pop_max = excluded.pop_max,
+			condition = excluded.condition, icon = excluded.icon, partial = excluded.partial
+		WHERE NOT excluded.partial OR daily_summaries.partial
+	`
+	qStaleSummaries := `
+		DELETE FROM daily_summaries
+		USING (SELECT city_id, min(date) AS date FROM daily_summaries_staging GROUP BY city_id) AS earliest
+		WHERE daily_summaries.city_id = earliest.city_id
+		AND daily_summaries.date >= earliest.date
+		AND NOT EXISTS (
+			SELECT 1 FROM daily_summaries_staging
+			WHERE daily_summaries_staging.city_id = daily_summaries.city_id AND daily_summaries_staging.date = daily_summaries.date
+		)
+	`
+
+	var forecasts, slots, summaries [][]any
+	for _, city := range cities {
+		if len(city.Forecasts) == 0 {
+			continue
+		}
+		for _, f := range city.Forecasts {
+			forecasts = append(forecasts, []any{len(forecasts), f.Temp, f.Date, f.AdditionalInfo, city.CityID})
+		}
+		for _, s := range city.Slots {
+			slots = append(slots, []any{len(slots), s.CityID, s.Provider, s.IssuedAt, s.ValidAt, s.Temp, s.Pop, s.Precipitation})
+		}
+		for _, s := range city.Summaries {
+			summaries = append(summaries, []any{len(summaries), city.CityID, s.Date, s.UTCOffset, s.TempMin, s.TempMax,
+				s.TempMean, s.TempMidday, s.Precipitation, s.WindMax, s.GustMax, s.PopMax, s.Condition, s.Icon, s.Partial})
+		}
+	}
+	if len(forecasts) == 0 {
+		return nil
+	}
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	log.Println("SQL Query:", formatQuery(qStaging))
+	if _, err := tx.Exec(ctx, qStaging); err != nil {
+		return err
+	}
+
+	copies := []struct {
+		table   string
+		columns []string
+		rows    [][]any
+	}{
+		{"forecasts_staging", []string{"ord", "temp", "date", "additional_info", "city_id"}, forecasts},
+		{"forecast_history_staging", []string{"ord", "city_id", "provider", "issued_at", "valid_at", "temp", "pop", "precipitation"}, slots},
+		{"daily_summaries_staging", []string{"ord", "city_id", "date", "utc_offset", "temp_min", "temp_max", "temp_mean", "temp_midday",
+			"precipitation", "wind_max", "gust_max", "pop_max", "condition", "icon", "partial"}, summaries},
+	}
+	for _, c := range copies {
+		log.Println("COPY", c.table+":", len(c.rows), "rows")
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{c.table}, c.columns, pgx.CopyFromRows(c.rows)); err != nil {
+			return err
+		}
+	}
+
+	for _, q := range []string{qForecasts, qStale, qSlots, qSummaries, qStaleSummaries} {
+		log.Println("SQL Query:", formatQuery(q))
+		if _, err := tx.Exec(ctx, q); err != nil {
+			return checkError(err)
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"weather_service/internal/database"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ForecastChangesChannel - channel of NOTIFY sent by ReplaceCityForecasts and ReplaceForecasts with JSON of database.ForecastChange
+const ForecastChangesChannel = "forecast_changes"
+
+// acquirer is implemented by pgxpool.Pool, LISTEN needs a dedicated connection
+type acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// ListenForecastChanges listens ForecastChangesChannel on a dedicated connection of primary, calls ready after LISTEN
+// if it is not nil and handle for every notification until ctx is done or the connection fails
+func (r *PostgresRepository) ListenForecastChanges(ctx context.Context, ready func(), handle func(database.ForecastChange)) error {
+	pool, ok := r.client.(acquirer)
+	if !ok {
+		return errors.New("client can not acquire connection for LISTEN")
+	}
+
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// connection stays subscribed, so it is taken from the pool and closed instead of being released
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	q := `LISTEN ` + ForecastChangesChannel
+	log.Println("SQL Query:", q)
+	if _, err := conn.Exec(ctx, q); err != nil {
+		return err
+	}
+	if ready != nil {
+		ready()
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var change database.ForecastChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			log.Println("Wrong forecast change:", notification.Payload, "error", err)
+			continue
+		}
+		handle(change)
+	}
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// partitionSuffix - layout of month suffix in partition names made by create_monthly_partition in migrate.sql
+const partitionSuffix = "_y2006m01"
+
+// CreatePartitions makes sure forecasts and forecast_history have partitions for every month from from to to inclusive
+func (r *PostgresRepository) CreatePartitions(ctx context.Context, from, to time.Time) error {
+	q := `
+		SELECT create_monthly_partition(parent, month::date)
+		FROM unnest(ARRAY['forecasts', 'forecast_history']) AS parent,
+		generate_series(date_trunc('month', $1::timestamp), $2::timestamp, INTERVAL '1 month') AS month
+	`
+
+	from, to = from.UTC(), to.UTC()
+	log.Println("SQL Query:", formatQuery(q), from.Format("2006-01-02"), to.Format("2006-01-02"))
+	_, err := r.client.Exec(ctx, q, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	return err
+}
+
+// DropForecastPartitions drops partitions of forecasts that only hold dates before before
+func (r *PostgresRepository) DropForecastPartitions(ctx context.Context, before time.Time) (int, error) {
+	return r.dropPartitions(ctx, "forecasts", before)
+}
+
+// DropForecastHistoryPartitions drops partitions of forecast_history that only hold slots valid before before
+func (r *PostgresRepository) DropForecastHistoryPartitions(ctx context.Context, before time.Time) (int, error) {
+	return r.dropPartitions(ctx, "forecast_history", before)
+}
+
+// dropPartitions drops monthly partitions of parent whose month ends not later than before,
+// partition holding before stays and is purged row by row
+func (r *PostgresRepository) dropPartitions(ctx context.Context, parent string, before time.Time) (int, error) {
+	q := `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass
+		ORDER BY c.relname
+	`
+
+	log.Println("SQL Query:", formatQuery(q), parent)
+	rows, err := r.client.Query(ctx, q, parent)
+	if err != nil {
+		log.Println("Query error:", err)
+		return 0, err
+	}
+	partitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		log.Println("Error scanning row:", err)
+		return 0, err
+	}
+
+	dropped := 0
+	for _, partition := range partitions {
+		month, err := time.Parse(partitionSuffix, strings.TrimPrefix(partition, parent))
+		if err != nil {
+			// not created by create_monthly_partition
+			continue
+		}
+		if month.AddDate(0, 1, 0).After(before) {
+			continue
+		}
+
+		q := `DROP TABLE ` + pgx.Identifier{partition}.Sanitize()
+		log.Println("SQL Query:", q)
+		if _, err := r.client.Exec(ctx, q); err != nil {
+			return dropped, err
+		}
+		dropped++
+	}
+	return dropped, nil
+}
@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"log"
-	"sort"
 	"strings"
 	"time"
 	"weather_service/internal/database"
@@ -15,15 +15,25 @@ import (
 	"weather_service/pkg/client"
 )
 
-// PostgresRepository implements Repository
+// PostgresRepository implements Repository, writes go to client and reads to reader
 type PostgresRepository struct {
 	client client.Client
+	reader client.Client
 }
 
 // NewPostgresRepository creates a new PostgresRepository returns interface
 func NewPostgresRepository(client client.Client) database.Repository {
 	return &PostgresRepository{
 		client: client,
+		reader: client,
+	}
+}
+
+// NewPostgresRepositoryWithReader creates a new PostgresRepository with separate client for reads, like a read replica
+func NewPostgresRepositoryWithReader(client, reader client.Client) database.Repository {
+	return &PostgresRepository{
+		client: client,
+		reader: reader,
 	}
 }
 
@@ -32,11 +42,42 @@ func formatQuery(q string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
 }
 
-// CreateCity creates a new city in database
+// foreignKeyViolation - SQLSTATE of insert referencing unknown city
+const foreignKeyViolation = "23503"
+
+// checkError logs SQL error and converts foreign key violation to database.ErrCityNotFound
+func checkError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		log.Println(pgErr)
+		if pgErr.Code == foreignKeyViolation {
+			return database.ErrCityNotFound
+		}
+	}
+	return err
+}
+
+// notFound returns database.ErrCityNotFound if city does not exist, otherwise err
+func (r *PostgresRepository) notFound(ctx context.Context, cityID int, err error) error {
+	q := `SELECT EXISTS (SELECT 1 FROM cities WHERE id = $1)`
+
+	var exists bool
+	if existsErr := r.reader.QueryRow(ctx, q, cityID).Scan(&exists); existsErr != nil {
+		return existsErr
+	}
+	if !exists {
+		return database.ErrCityNotFound
+	}
+	return err
+}
+
+// CreateCity creates a new city in database or refreshes coordinates of an existing one
 func (r *PostgresRepository) CreateCity(ctx context.Context, city *models.City) error {
 	q := `
 	INSERT INTO cities
 	    (city, country, lat, long) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (city, country)
+		DO UPDATE SET lat = excluded.lat, long = excluded.long
 		RETURNING id
 	
 	`
@@ -57,15 +98,60 @@ func (r *PostgresRepository) CreateCity(ctx context.Context, city *models.City)
 
 // GetAllCities returns all cities from database
 func (r *PostgresRepository) GetAllCities(ctx context.Context) ([]models.City, error) {
-	q := `SELECT id, city, country, lat, long FROM cities`
+	q := `SELECT id, city, country, lat, long FROM cities ORDER BY city COLLATE "C", id`
 	// get all cities from database
-	rows, err := r.client.Query(ctx, q)
+	rows, err := r.reader.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanCities(rows)
+}
+
+// ListCities returns a page of cities selected by filter, after the cursor in sort order
+func (r *PostgresRepository) ListCities(ctx context.Context, filter database.CityFilter) ([]models.City, error) {
+	columns, values := filter.SortKey()
+	// compare names byte-wise as sqlite and memory repositories do, whatever the database collation is
+	for i, column := range columns {
+		if column == "city" || column == "country" {
+			columns[i] = column + ` COLLATE "C"`
+		}
+	}
+	q := `
+		SELECT id, city, country, lat, long
+		FROM cities
+		WHERE ($1 = '' OR country = $1)`
+	args := []interface{}{filter.Country}
+	if filter.After != nil {
+		placeholders := make([]string, len(values))
+		for i := range values {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+		}
+		q += fmt.Sprintf("\n\t\tAND (%s) > (%s)", strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+		args = append(args, values...)
+	}
+	q += "\n\t\tORDER BY " + strings.Join(columns, ", ")
+	if filter.Limit > 0 {
+		q += fmt.Sprintf("\n\t\tLIMIT $%d", len(args)+1)
+		args = append(args, filter.Limit)
+	}
+
+	log.Println("SQL Query:", formatQuery(q), args)
+	rows, err := r.reader.Query(ctx, q, args...)
 	if err != nil {
+		log.Println("Query error:", err)
 		return nil, err
 	}
 
 	defer rows.Close()
 
+	return scanCities(rows)
+}
+
+// scanCities reads cities selected as id, city, country, lat, long
+func scanCities(rows pgx.Rows) ([]models.City, error) {
 	// create slice of cities
 	cities := make([]models.City, 0)
 	for rows.Next() {
@@ -79,12 +165,6 @@ func (r *PostgresRepository) GetAllCities(ctx context.Context) ([]models.City, e
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-
-	// sort cities by city name
-	sort.Slice(cities, func(i, j int) bool {
-		return cities[i].Name < cities[j].Name
-	})
-
 	return cities, nil
 }
 
@@ -102,28 +182,123 @@ func (r *PostgresRepository) CreateForecast(ctx context.Context, forecast *model
 	// insert new forecast for concrete city in database
 	_, err := r.client.Exec(ctx, q, forecast.Temp, forecast.Date, forecast.AdditionalInfo, cityID)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			log.Println(pgErr)
+		return checkError(err)
+	}
+	return nil
+}
+
+// ReplaceCityForecasts saves forecasts of one city and issued slots in one transaction using a single batch,
+// days from the earliest new date on that are missing in forecasts are deleted, daily summaries are replaced the same way.
+// Nothing is changed on error. Listeners of ForecastChangesChannel are notified about every changed date after commit
+func (r *PostgresRepository) ReplaceCityForecasts(ctx context.Context, cityID int, forecasts []models.WeatherInfo, slots []models.ForecastSlot, summaries []models.DailySummary) error {
+	if len(forecasts) == 0 {
+		return nil
+	}
+
+	qForecast := `
+		INSERT INTO forecasts
+		(temp, date, additional_info, city_id)
+		VALUES ($1, $2, $3, $4)
+
+		ON CONFLICT (city_id, date)
+		DO UPDATE SET temp = excluded.temp, additional_info = excluded.additional_info
+	`
+	// notifications are delivered only when transaction commits, one for every saved or deleted date
+	qStale := `
+		WITH stale AS (
+			DELETE FROM forecasts
+			WHERE city_id = $1
+			AND date >= $2::date
+			AND NOT date = ANY($3::date[])
+			RETURNING date
+		)
+		SELECT pg_notify('` + ForecastChangesChannel + `', json_build_object('city_id', $1::int, 'date', date)::text)
+		FROM (SELECT date FROM stale UNION SELECT unnest($3::date[])) AS changed
+	`
+	qSlot := `
+		INSERT INTO forecast_history
+			(city_id, provider, issued_at, valid_at, temp, pop, precipitation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+
+		ON CONFLICT (city_id, provider, issued_at, valid_at)
+		DO UPDATE SET temp = excluded.temp, pop = excluded.pop, precipitation = excluded.precipitation
+	`
+	qSummary := `
+		INSERT INTO daily_summaries
+			(city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday, precipitation, wind_max, gust_max, pop_max, condition, icon, partial)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+
+		ON CONFLICT (city_id, date)
+		DO UPDATE SET utc_offset = excluded.utc_offset, temp_min = excluded.temp_min, temp_max = excluded.temp_max,
+			temp_mean = excluded.temp_mean, temp_midday = excluded.temp_midday, precipitation = excluded.precipitation,
+			wind_max = excluded.wind_max, gust_max = excluded.gust_max, pop_max = excluded.pop_max,
+			condition = excluded.condition, icon = excluded.icon, partial = excluded.partial
+		WHERE NOT excluded.partial OR daily_summaries.partial
+	`
+	qStaleSummaries := `
+		DELETE FROM daily_summaries
+		WHERE city_id = $1
+		AND date >= $2::date
+		AND NOT date = ANY($3::date[])
+	`
+
+	batch := &pgx.Batch{}
+	dates := make([]string, 0, len(forecasts))
+	from := forecasts[0].Date.Format("2006-01-02")
+	for _, forecast := range forecasts {
+		batch.Queue(qForecast, forecast.Temp, forecast.Date, forecast.AdditionalInfo, cityID)
+		date := forecast.Date.Format("2006-01-02")
+		dates = append(dates, date)
+		if date < from {
+			from = date
 		}
+	}
+	batch.Queue(qStale, cityID, from, dates)
+	for _, slot := range slots {
+		batch.Queue(qSlot, slot.CityID, slot.Provider, slot.IssuedAt, slot.ValidAt, slot.Temp, slot.Pop, slot.Precipitation)
+	}
+	summaryDates := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		batch.Queue(qSummary, cityID, summary.Date, summary.UTCOffset, summary.TempMin, summary.TempMax, summary.TempMean,
+			summary.TempMidday, summary.Precipitation, summary.WindMax, summary.GustMax, summary.PopMax, summary.Condition, summary.Icon, summary.Partial)
+		summaryDates = append(summaryDates, summary.Date.Format("2006-01-02"))
+	}
+	if len(summaries) > 0 {
+		// summaries are ordered by date
+		batch.Queue(qStaleSummaries, cityID, summaryDates[0], summaryDates)
+	}
+
+	log.Println("SQL Query:", formatQuery(qForecast), cityID, len(forecasts), "forecasts")
+	log.Println("SQL Query:", formatQuery(qStale), cityID, from, dates)
+	log.Println("SQL Query:", formatQuery(qSlot), len(slots), "slots")
+	log.Println("SQL Query:", formatQuery(qSummary), len(summaries), "summaries")
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return checkError(err)
+	}
+	return tx.Commit(ctx)
 }
 
-// GetShortForecastByCityID returns short forecast for concrete city
+// GetShortForecastByCityID returns short forecast for concrete city from daily summaries of local today and later days,
+// average temperature is the mean of midday temperatures
 func (r *PostgresRepository) GetShortForecastByCityID(ctx context.Context, cityID int) (*models.ShortForecast, error) {
-	q := `SELECT forecasts.temp, forecasts.date, cities.city, cities.country
-		FROM forecasts
-		JOIN cities ON cities.id = forecasts.city_id
+	q := `SELECT daily_summaries.temp_midday, daily_summaries.date, cities.city, cities.country
+		FROM daily_summaries
+		JOIN cities ON cities.id = daily_summaries.city_id
 		WHERE city_id = $1
-  		AND EXTRACT(DOW FROM date) >= EXTRACT(DOW FROM CURRENT_DATE)
+		AND date >= (NOW() AT TIME ZONE 'UTC' + utc_offset * INTERVAL '1 second')::date
 		ORDER BY date
 	`
 
 	log.Println("SQL Query:", formatQuery(q), cityID)
 	// get short forecast for 5 days for concrete city from database
-	rows, err := r.client.Query(ctx, q, cityID)
+	rows, err := r.reader.Query(ctx, q, cityID)
 	if err != nil {
 		log.Println("Query error:", err)
 		return nil, err
@@ -131,7 +306,6 @@ func (r *PostgresRepository) GetShortForecastByCityID(ctx context.Context, cityI
 
 	defer rows.Close()
 
-	// scan short forecast from database
 	var temp float64
 	var sumTemp float64
 	var count = 0
@@ -154,19 +328,92 @@ func (r *PostgresRepository) GetShortForecastByCityID(ctx context.Context, cityI
 		log.Println("Error scanning row:", err)
 		return nil, err
 	}
-	// calculate average temperature for 5 days
-	avgtemp := sumTemp / float64(count)
+	if count == 0 {
+		return nil, r.notFound(ctx, cityID, database.ErrForecastNotFound)
+	}
 
-	// create short forecast
-	shortForecast := models.ShortForecast{
+	return &models.ShortForecast{
 		City:     city,
 		Country:  country,
-		AvgTemp:  avgtemp,
+		AvgTemp:  sumTemp / float64(count),
 		DateList: dateSlice,
+	}, nil
+}
+
+// GetDailySummaries returns daily summaries of concrete city from its local today on
+func (r *PostgresRepository) GetDailySummaries(ctx context.Context, cityID int) ([]models.DailySummary, error) {
+	q := `SELECT city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday,
+			precipitation, wind_max, gust_max, pop_max, condition, icon, partial
+		FROM daily_summaries
+		WHERE city_id = $1
+		AND date >= (NOW() AT TIME ZONE 'UTC' + utc_offset * INTERVAL '1 second')::date
+		ORDER BY date
+	`
+
+	log.Println("SQL Query:", formatQuery(q), cityID)
+	rows, err := r.reader.Query(ctx, q, cityID)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries, err := scanSummaries(rows)
+	if err != nil {
+		return nil, err
 	}
+	if len(summaries) == 0 {
+		return nil, r.notFound(ctx, cityID, database.ErrForecastNotFound)
+	}
+	return summaries, nil
+}
 
-	return &shortForecast, nil
+// GetCitiesDailySummaries returns daily summaries of cities from their local today on grouped by city
+func (r *PostgresRepository) GetCitiesDailySummaries(ctx context.Context, cityIDs []int) (map[int][]models.DailySummary, error) {
+	q := `SELECT city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday,
+			precipitation, wind_max, gust_max, pop_max, condition, icon, partial
+		FROM daily_summaries
+		WHERE ($1 OR city_id = ANY($2))
+		AND date >= (NOW() AT TIME ZONE 'UTC' + utc_offset * INTERVAL '1 second')::date
+		ORDER BY city_id, date
+	`
+
+	log.Println("SQL Query:", formatQuery(q), cityIDs)
+	rows, err := r.reader.Query(ctx, q, cityIDs == nil, cityIDs)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries, err := scanSummaries(rows)
+	if err != nil {
+		return nil, err
+	}
+	byCity := make(map[int][]models.DailySummary)
+	for _, summary := range summaries {
+		byCity[summary.CityID] = append(byCity[summary.CityID], summary)
+	}
+	return byCity, nil
+}
 
+// scanSummaries reads daily summaries selected in the column order of GetDailySummaries
+func scanSummaries(rows pgx.Rows) ([]models.DailySummary, error) {
+	summaries := make([]models.DailySummary, 0)
+	for rows.Next() {
+		var s models.DailySummary
+		if err := rows.Scan(&s.CityID, &s.Date, &s.UTCOffset, &s.TempMin, &s.TempMax, &s.TempMean, &s.TempMidday,
+			&s.Precipitation, &s.WindMax, &s.GustMax, &s.PopMax, &s.Condition, &s.Icon, &s.Partial); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	return summaries, nil
 }
 
 // GetForecastByCityIDandDate returns forecasts for concrete date
@@ -178,9 +425,14 @@ func (r *PostgresRepository) GetForecastByCityIDandDate(ctx context.Context, cit
 		AND date = $2
 		ORDER BY date`
 
+	day, err := database.ParseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("SQL Query:", formatQuery(q), cityID, date)
 	// get forecasts for concrete date for concrete city from database
-	rows, err := r.client.Query(ctx, q, cityID, date)
+	rows, err := r.reader.Query(ctx, q, cityID, day)
 	if err != nil {
 		log.Println("Query error:", err)
 		return nil, err
@@ -210,6 +462,9 @@ func (r *PostgresRepository) GetForecastByCityIDandDate(ctx context.Context, cit
 		log.Println("Error scanning row:", err)
 		return nil, err
 	}
+	if len(forecasts) == 0 {
+		return nil, r.notFound(ctx, cityID, database.ErrForecastNotFound)
+	}
 
 	return forecasts, nil
 
@@ -217,6 +472,9 @@ func (r *PostgresRepository) GetForecastByCityIDandDate(ctx context.Context, cit
 
 // GetForecastByCityIDandDateTime returns forecast for concrete date and time
 func (r *PostgresRepository) GetForecastByCityIDandDateTime(ctx context.Context, cityID int, date, time string) (*models.List, error) {
+	if err := database.ValidateClock(time); err != nil {
+		return nil, err
+	}
 	// get forecast for concrete date for concrete city from database
 	forecasts, err := r.GetForecastByCityIDandDate(ctx, cityID, date)
 	if err != nil {
@@ -232,6 +490,420 @@ func (r *PostgresRepository) GetForecastByCityIDandDateTime(ctx context.Context,
 		}
 	}
 	// if no forecast found
-	err = errors.New("no forecast found")
-	return nil, err
+	return nil, database.ErrForecastNotFound
+}
+
+// GetForecastRange returns 3-hour slots of concrete city valid in [from, to] ordered by time
+func (r *PostgresRepository) GetForecastRange(ctx context.Context, cityID int, from, to time.Time) ([]models.List, error) {
+	// date bounds let the planner prune monthly partitions, dt filters slots inside the days
+	q := `
+		SELECT slot
+		FROM forecasts, jsonb_array_elements(forecasts.additional_info) AS slot
+		WHERE city_id = $1
+		AND date BETWEEN $2 AND $3
+		AND (slot->>'dt')::bigint BETWEEN $4 AND $5
+		ORDER BY (slot->>'dt')::bigint`
+
+	fromDay := from.UTC().Truncate(24 * time.Hour)
+	toDay := to.UTC().Truncate(24 * time.Hour)
+
+	log.Println("SQL Query:", formatQuery(q), cityID, from, to)
+	rows, err := r.reader.Query(ctx, q, cityID, fromDay, toDay, from.Unix(), to.Unix())
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	slots := make([]models.List, 0)
+	for rows.Next() {
+		var value json.RawMessage
+		if err := rows.Scan(&value); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		var slot models.List
+		if err := json.Unmarshal(value, &slot); err != nil {
+			log.Println("Unmarshal error:", err, " slot:", value)
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if len(slots) == 0 {
+		if err := r.notFound(ctx, cityID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return slots, nil
+}
+
+// GetCitiesForecastRange returns 3-hour slots of cities valid in [from, to] grouped by city
+func (r *PostgresRepository) GetCitiesForecastRange(ctx context.Context, cityIDs []int, from, to time.Time) (map[int][]models.List, error) {
+	q := `
+		SELECT city_id, slot
+		FROM forecasts, jsonb_array_elements(forecasts.additional_info) AS slot
+		WHERE ($1 OR city_id = ANY($2))
+		AND date BETWEEN $3 AND $4
+		AND (slot->>'dt')::bigint BETWEEN $5 AND $6
+		ORDER BY city_id, (slot->>'dt')::bigint`
+
+	fromDay := from.UTC().Truncate(24 * time.Hour)
+	toDay := to.UTC().Truncate(24 * time.Hour)
+
+	log.Println("SQL Query:", formatQuery(q), cityIDs, from, to)
+	rows, err := r.reader.Query(ctx, q, cityIDs == nil, cityIDs, fromDay, toDay, from.Unix(), to.Unix())
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	slots := make(map[int][]models.List)
+	for rows.Next() {
+		var cityID int
+		var value json.RawMessage
+		if err := rows.Scan(&cityID, &value); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		var slot models.List
+		if err := json.Unmarshal(value, &slot); err != nil {
+			log.Println("Unmarshal error:", err, " slot:", value)
+			return nil, err
+		}
+		slots[cityID] = append(slots[cityID], slot)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+
+	return slots, nil
+}
+
+// PurgeForecastSlots drops 3-hour slots from up to limit forecasts older than before, keeping the daily temperature
+func (r *PostgresRepository) PurgeForecastSlots(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		UPDATE forecasts
+		SET additional_info = '[]'::jsonb
+		WHERE date < $1
+		AND (id, date) IN (
+			SELECT id, date FROM forecasts
+			WHERE date < $1
+			AND additional_info <> '[]'::jsonb
+			LIMIT $2
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	tag, err := r.client.Exec(ctx, q, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeForecasts deletes up to limit forecasts older than before
+func (r *PostgresRepository) PurgeForecasts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		DELETE FROM forecasts
+		WHERE date < $1
+		AND (id, date) IN (
+			SELECT id, date FROM forecasts
+			WHERE date < $1
+			LIMIT $2
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	tag, err := r.client.Exec(ctx, q, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeDailySummaries deletes up to limit daily summaries older than before
+func (r *PostgresRepository) PurgeDailySummaries(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		DELETE FROM daily_summaries
+		WHERE (city_id, date) IN (
+			SELECT city_id, date FROM daily_summaries
+			WHERE date < $1
+			LIMIT $2
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	tag, err := r.client.Exec(ctx, q, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CreateObservation saves measured weather for concrete city, a repeated reading from the same source replaces the old one
+func (r *PostgresRepository) CreateObservation(ctx context.Context, observation *models.Observation) error {
+	q := `
+		INSERT INTO observations
+			(city_id, observed_at, source, temp, humidity, pressure, wind_speed, wind_deg, clouds, precipitation, condition)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
+
+		ON CONFLICT (city_id, observed_at, source)
+		DO UPDATE SET temp = excluded.temp, humidity = excluded.humidity, pressure = excluded.pressure,
+			wind_speed = excluded.wind_speed, wind_deg = excluded.wind_deg, clouds = excluded.clouds,
+			precipitation = excluded.precipitation, condition = excluded.condition
+		RETURNING id
+	`
+
+	log.Println("SQL Query:", formatQuery(q), observation.CityID, observation.ObservedAt, observation.Source)
+	err := r.client.QueryRow(ctx, q,
+		observation.CityID, observation.ObservedAt, observation.Source, observation.Temp,
+		observation.Humidity, observation.Pressure, observation.WindSpeed, observation.WindDeg,
+		observation.Clouds, observation.Precipitation, observation.Condition,
+	).Scan(&observation.ID)
+	if err != nil {
+		return checkError(err)
+	}
+	return nil
+}
+
+// GetObservations returns observations for concrete city in [from, to) ordered by time
+func (r *PostgresRepository) GetObservations(ctx context.Context, cityID int, from, to time.Time) ([]models.Observation, error) {
+	q := `
+		SELECT id, city_id, observed_at, source, temp, humidity, pressure, wind_speed, wind_deg, clouds, precipitation, COALESCE(condition, '')
+		FROM observations
+		WHERE city_id = $1
+		AND observed_at >= $2
+		AND observed_at < $3
+		ORDER BY observed_at, source`
+
+	log.Println("SQL Query:", formatQuery(q), cityID, from, to)
+	rows, err := r.reader.Query(ctx, q, cityID, from, to)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	observations := make([]models.Observation, 0)
+	for rows.Next() {
+		var o models.Observation
+		if err := rows.Scan(&o.ID, &o.CityID, &o.ObservedAt, &o.Source, &o.Temp, &o.Humidity, &o.Pressure,
+			&o.WindSpeed, &o.WindDeg, &o.Clouds, &o.Precipitation, &o.Condition); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		o.ObservedAt = o.ObservedAt.UTC()
+		observations = append(observations, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if len(observations) == 0 {
+		if err := r.notFound(ctx, cityID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return observations, nil
+}
+
+// CreateForecastSlots saves issued forecast slots, a repeated slot of the same run replaces the old one
+func (r *PostgresRepository) CreateForecastSlots(ctx context.Context, slots []models.ForecastSlot) error {
+	q := `
+		INSERT INTO forecast_history
+			(city_id, provider, issued_at, valid_at, temp, pop, precipitation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+
+		ON CONFLICT (city_id, provider, issued_at, valid_at)
+		DO UPDATE SET temp = excluded.temp, pop = excluded.pop, precipitation = excluded.precipitation
+	`
+
+	log.Println("SQL Query:", formatQuery(q), len(slots), "slots")
+	batch := &pgx.Batch{}
+	for _, slot := range slots {
+		batch.Queue(q, slot.CityID, slot.Provider, slot.IssuedAt, slot.ValidAt, slot.Temp, slot.Pop, slot.Precipitation)
+	}
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return checkError(err)
+	}
+	return tx.Commit(ctx)
+}
+
+// PurgeForecastHistory deletes up to limit issued forecast slots valid before before
+func (r *PostgresRepository) PurgeForecastHistory(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		DELETE FROM forecast_history
+		WHERE valid_at < $1
+		AND (id, valid_at) IN (
+			SELECT id, valid_at FROM forecast_history
+			WHERE valid_at < $1
+			LIMIT $2
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	tag, err := r.client.Exec(ctx, q, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetVerificationPairs matches every issued forecast slot valid in [from, to) with the nearest observation within an hour,
+// pairs are ordered by city, valid time, issue time and provider
+func (r *PostgresRepository) GetVerificationPairs(ctx context.Context, filter database.VerificationFilter) ([]models.VerificationPair, error) {
+	q := `
+		SELECT h.city_id, h.provider, h.issued_at, h.valid_at, h.temp, h.pop,
+			o.observed_at, o.temp, o.precipitation, COALESCE(o.condition, '')
+		FROM forecast_history h
+		JOIN LATERAL (
+			SELECT observed_at, temp, precipitation, condition
+			FROM observations
+			WHERE city_id = h.city_id
+			AND observed_at BETWEEN h.valid_at - INTERVAL '1 hour' AND h.valid_at + INTERVAL '1 hour'
+			ORDER BY ABS(EXTRACT(EPOCH FROM observed_at - h.valid_at)), source
+			LIMIT 1
+		) o ON TRUE
+		WHERE ($1::int = 0 OR h.city_id = $1)
+		AND ($2::varchar = '' OR h.provider = $2)
+		AND h.valid_at >= $3
+		AND h.valid_at < $4
+		ORDER BY h.city_id, h.valid_at, h.issued_at, h.provider`
+
+	log.Println("SQL Query:", formatQuery(q), filter.CityID, filter.Provider, filter.From, filter.To)
+	rows, err := r.reader.Query(ctx, q, filter.CityID, filter.Provider, filter.From, filter.To)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	pairs := make([]models.VerificationPair, 0)
+	for rows.Next() {
+		var p models.VerificationPair
+		if err := rows.Scan(&p.CityID, &p.Provider, &p.IssuedAt, &p.ValidAt, &p.ForecastTemp, &p.ForecastPop,
+			&p.ObservedAt, &p.ObservedTemp, &p.ObservedPrecipitation, &p.ObservedCondition); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if len(pairs) == 0 && filter.CityID != 0 {
+		if err := r.notFound(ctx, filter.CityID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return pairs, nil
+}
+
+// CreateBiasCorrection saves trained corrections as a new version and sets correction.Version
+func (r *PostgresRepository) CreateBiasCorrection(ctx context.Context, correction *models.BiasCorrection) error {
+	q := `
+		INSERT INTO bias_corrections
+			(trained_at, window_from, window_to, min_samples)
+		VALUES ($1, $2, $3, $4)
+		RETURNING version
+	`
+	qOffset := `
+		INSERT INTO bias_correction_offsets
+			(version, city_id, hour, lead_hours, bias, samples)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	log.Println("SQL Query:", formatQuery(q), correction.TrainedAt, correction.From, correction.To, correction.MinSamples)
+	if err := tx.QueryRow(ctx, q, correction.TrainedAt, correction.From, correction.To, correction.MinSamples).Scan(&correction.Version); err != nil {
+		return err
+	}
+
+	log.Println("SQL Query:", formatQuery(qOffset), len(correction.Offsets), "offsets")
+	batch := &pgx.Batch{}
+	for _, o := range correction.Offsets {
+		batch.Queue(qOffset, correction.Version, o.CityID, o.Hour, o.LeadHours, o.Bias, o.Samples)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return checkError(err)
+	}
+	return tx.Commit(ctx)
+}
+
+// GetBiasCorrection returns corrections of concrete version, version 0 means the latest one.
+// Returns nil if there is no such version
+func (r *PostgresRepository) GetBiasCorrection(ctx context.Context, version int) (*models.BiasCorrection, error) {
+	q := `
+		SELECT version, trained_at, window_from, window_to, min_samples
+		FROM bias_corrections
+		WHERE $1::int = 0 OR version = $1
+		ORDER BY version DESC
+		LIMIT 1`
+	qOffset := `
+		SELECT city_id, hour, lead_hours, bias, samples
+		FROM bias_correction_offsets
+		WHERE version = $1
+		ORDER BY city_id, hour, lead_hours`
+
+	log.Println("SQL Query:", formatQuery(q), version)
+	var correction models.BiasCorrection
+	err := r.reader.QueryRow(ctx, q, version).Scan(&correction.Version, &correction.TrainedAt, &correction.From, &correction.To, &correction.MinSamples)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("SQL Query:", formatQuery(qOffset), correction.Version)
+	rows, err := r.reader.Query(ctx, qOffset, correction.Version)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	correction.Offsets = make([]models.BiasOffset, 0)
+	for rows.Next() {
+		var o models.BiasOffset
+		if err := rows.Scan(&o.CityID, &o.Hour, &o.LeadHours, &o.Bias, &o.Samples); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		correction.Offsets = append(correction.Offsets, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+
+	return &correction, nil
 }
@@ -0,0 +1,343 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+	"weather_service/internal/config"
+	"weather_service/internal/database"
+	"weather_service/internal/database/databasetest"
+	"weather_service/internal/models"
+	"weather_service/internal/partitioning"
+	"weather_service/pkg/client"
+)
+
+// TestMain runs tests from the repository root where config.yml and migrate.sql are
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestClient connects to PostgreSQL from config.yml on host TEST_DATABASE_HOST,
+// tests are skipped when it is not set. All tables are truncated
+func newTestClient(t testing.TB) client.Client {
+	host := os.Getenv("TEST_DATABASE_HOST")
+	if host == "" {
+		t.Skip("TEST_DATABASE_HOST is not set")
+	}
+
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Database.Host = host
+
+	pool, err := client.NewClient(context.Background(), *cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	q := `TRUNCATE cities, forecasts, daily_summaries, observations, forecast_history, bias_corrections, bias_correction_offsets RESTART IDENTITY CASCADE`
+	if _, err := pool.Exec(context.Background(), q); err != nil {
+		t.Fatal(err)
+	}
+
+	// conformance suite writes forecasts of July 2024 and of today
+	repo := NewPostgresRepository(pool).(database.PartitionManager)
+	if err := repo.CreatePartitions(context.Background(), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), time.Now().AddDate(0, 1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestPostgresRepository(t *testing.T) {
+	databasetest.TestRepository(t, func(t *testing.T) database.Repository {
+		return NewPostgresRepository(newTestClient(t))
+	})
+}
+
+func TestPartitions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewPostgresRepository(newTestClient(t))
+	partitions := repo.(database.PartitionManager)
+
+	january := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := partitions.CreatePartitions(ctx, january.AddDate(0, 0, 14), january.AddDate(0, 2, 0)); err != nil {
+		t.Fatalf("CreatePartitions: %v", err)
+	}
+	// creating existing partitions again is fine
+	if err := partitions.CreatePartitions(ctx, january, january.AddDate(0, 1, 0)); err != nil {
+		t.Fatalf("CreatePartitions again: %v", err)
+	}
+
+	city := models.City{Name: "Berlin", Country: "DE"}
+	if err := repo.CreateCity(ctx, &city); err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []time.Time{january.AddDate(0, 0, 20), january.AddDate(0, 1, 10)} {
+		forecast := models.WeatherInfo{Temp: 5, Date: day}
+		if err := repo.CreateForecast(ctx, &forecast, city.ID); err != nil {
+			t.Fatalf("CreateForecast(%s): %v", day, err)
+		}
+	}
+
+	// February holds the cutoff, so only January is dropped
+	n, err := partitions.DropForecastPartitions(ctx, january.AddDate(0, 1, 15))
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 partition dropped, got %d, %v", n, err)
+	}
+	if _, err := repo.GetForecastByCityIDandDate(ctx, city.ID, "2020-01-21"); !errors.Is(err, database.ErrForecastNotFound) {
+		t.Errorf("Expected forecast of dropped partition to be gone, got %v", err)
+	}
+	if _, err := repo.GetForecastByCityIDandDate(ctx, city.ID, "2020-02-11"); err != nil {
+		t.Errorf("Expected forecast of kept partition, got %v", err)
+	}
+
+	n, err = partitions.DropForecastHistoryPartitions(ctx, january.AddDate(0, 3, 0))
+	if err != nil || n != 3 {
+		t.Errorf("Expected 3 history partitions dropped, got %d, %v", n, err)
+	}
+}
+
+func TestMaintainerCreatesPartitions(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestClient(t)
+	maintainer := partitioning.NewMaintainer(NewPostgresRepository(pool).(database.PartitionManager), 2, time.Hour)
+	defer maintainer.Stop()
+	maintainer.CreatePartitions()
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, parent := range []string{"forecasts", "forecast_history"} {
+		for i := 0; i <= 2; i++ {
+			name := parent + month.AddDate(0, i, 0).Format("_y2006m01")
+			var exists bool
+			q := `SELECT EXISTS (SELECT 1 FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = $1::regclass AND c.relname = $2)`
+			if err := pool.QueryRow(ctx, q, parent, name).Scan(&exists); err != nil {
+				t.Fatal(err)
+			}
+			if !exists {
+				t.Errorf("Expected partition %s", name)
+			}
+		}
+	}
+}
+
+func TestListenForecastChanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repo := NewPostgresRepository(newTestClient(t))
+	forecasts := createCities(t, repo, 1, 2)
+	cityID := forecasts[0].CityID
+
+	changes := make(chan database.ForecastChange, 10)
+	listening := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.(database.ChangeListener).ListenForecastChanges(ctx, func() { close(listening) }, func(change database.ForecastChange) {
+			changes <- change
+		})
+	}()
+	// LISTEN has to be executed before the write
+	select {
+	case <-listening:
+	case err := <-done:
+		t.Fatalf("ListenForecastChanges: %v", err)
+	case <-ctx.Done():
+		t.Fatal("Expected LISTEN to be executed")
+	}
+
+	if err := repo.ReplaceCityForecasts(ctx, cityID, forecasts, nil, nil); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+	got := make(map[string]bool)
+	for len(got) < 2 {
+		select {
+		case change := <-changes:
+			if change.CityID != cityID {
+				t.Errorf("Expected change of city %d, got %+v", cityID, change)
+			}
+			got[change.Date] = true
+		case <-ctx.Done():
+			t.Fatalf("Expected 2 changed dates, got %v", got)
+		}
+	}
+	if !got["2024-07-11"] || !got["2024-07-12"] {
+		t.Errorf("Expected changes of saved dates, got %v", got)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected listening to stop with context, got %v", err)
+	}
+}
+
+// createCities creates n cities and returns forecasts for the next days days of every city
+func createCities(tb testing.TB, repo database.Repository, n, days int) []models.WeatherInfo {
+	tb.Helper()
+	ctx := context.Background()
+	day := time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC)
+
+	forecasts := make([]models.WeatherInfo, 0, n*days)
+	for i := 0; i < n; i++ {
+		city := models.City{Name: fmt.Sprintf("City %d", i), Country: "XX"}
+		if err := repo.CreateCity(ctx, &city); err != nil {
+			tb.Fatal(err)
+		}
+		for d := 0; d < days; d++ {
+			date := day.AddDate(0, 0, d)
+			slots := make([]models.List, 0, 8)
+			for hour := 0; hour < 24; hour += 3 {
+				dt := date.Add(time.Duration(hour) * time.Hour)
+				slots = append(slots, models.List{Dt: int(dt.Unix()), Main: models.Main{Temp: 20}, DtTime: dt})
+			}
+			forecasts = append(forecasts, models.WeatherInfo{CityID: city.ID, Temp: 20.7, Date: date, AdditionalInfo: slots})
+		}
+	}
+	return forecasts
+}
+
+// byCity groups forecasts by city for ReplaceForecasts
+func byCity(forecasts []models.WeatherInfo) []database.CityForecasts {
+	cities := make([]database.CityForecasts, 0)
+	for _, forecast := range forecasts {
+		if n := len(cities); n == 0 || cities[n-1].CityID != forecast.CityID {
+			cities = append(cities, database.CityForecasts{CityID: forecast.CityID})
+		}
+		cities[len(cities)-1].Forecasts = append(cities[len(cities)-1].Forecasts, forecast)
+	}
+	return cities
+}
+
+func TestReplaceForecasts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pool := newTestClient(t)
+	repo := NewPostgresRepository(pool)
+	bulk := repo.(database.BulkForecastWriter)
+
+	changes := make(chan database.ForecastChange, 20)
+	listening := make(chan struct{})
+	go repo.(database.ChangeListener).ListenForecastChanges(ctx, func() { close(listening) }, func(change database.ForecastChange) {
+		changes <- change
+	})
+	select {
+	case <-listening:
+	case <-ctx.Done():
+		t.Fatal("Expected LISTEN to be executed")
+	}
+	// waitChanges waits for n distinct changes
+	waitChanges := func(n int) map[database.ForecastChange]bool {
+		got := make(map[database.ForecastChange]bool)
+		for len(got) < n {
+			select {
+			case change := <-changes:
+				got[change] = true
+			case <-ctx.Done():
+				t.Fatalf("Expected %d changes, got %v", n, got)
+			}
+		}
+		return got
+	}
+
+	// three cities with 2024-07-11 and 2024-07-12 each
+	cities := byCity(createCities(t, repo, 3, 2))
+	first := &cities[0]
+	day := first.Forecasts[0].Date
+	// repeated city and date, the last one wins
+	again := first.Forecasts[0]
+	again.Temp = 25
+	first.Forecasts = append(first.Forecasts, again)
+	first.Slots = []models.ForecastSlot{{CityID: first.CityID, Provider: models.SourceOpenWeatherMap, IssuedAt: day, ValidAt: day, Temp: 25}}
+	first.Summaries = []models.DailySummary{{Date: day, TempMidday: 25}, {Date: day.AddDate(0, 0, 1), TempMidday: 21, Partial: true}}
+	if err := bulk.ReplaceForecasts(ctx, cities); err != nil {
+		t.Fatalf("ReplaceForecasts: %v", err)
+	}
+	waitChanges(6)
+
+	got, err := repo.GetForecastByCityIDandDate(ctx, first.CityID, "2024-07-11")
+	if err != nil || len(got) != 1 || got[0].Temp != 25 || len(got[0].AdditionalInfo) != 8 {
+		t.Errorf("Expected the last forecast for repeated day, got %+v, %v", got, err)
+	}
+	got, err = repo.GetForecastByCityIDandDate(ctx, cities[2].CityID, "2024-07-12")
+	if err != nil || len(got) != 1 || got[0].Temp != 20 {
+		t.Errorf("Expected temp truncated to 20, got %+v, %v", got, err)
+	}
+	var slots int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM forecast_history WHERE city_id = $1`, first.CityID).Scan(&slots); err != nil || slots != 1 {
+		t.Errorf("Expected 1 issued slot, got %d, %v", slots, err)
+	}
+
+	// the next run of the first city has only its first day with partial summary
+	next := database.CityForecasts{
+		CityID:    first.CityID,
+		Forecasts: first.Forecasts[:1],
+		Summaries: []models.DailySummary{{Date: day, TempMidday: 30, Partial: true}},
+	}
+	if err := bulk.ReplaceForecasts(ctx, []database.CityForecasts{next}); err != nil {
+		t.Fatalf("ReplaceForecasts: %v", err)
+	}
+	if deleted := (database.ForecastChange{CityID: first.CityID, Date: "2024-07-12"}); !waitChanges(2)[deleted] {
+		t.Errorf("Expected change of deleted day %+v", deleted)
+	}
+	if _, err := repo.GetForecastByCityIDandDate(ctx, first.CityID, "2024-07-12"); !errors.Is(err, database.ErrForecastNotFound) {
+		t.Errorf("Expected day missing in the next run to be deleted, got %v", err)
+	}
+	var midday float64
+	var partial bool
+	q := `SELECT temp_midday, partial FROM daily_summaries WHERE city_id = $1 AND date = $2`
+	if err := pool.QueryRow(ctx, q, first.CityID, day).Scan(&midday, &partial); err != nil || midday != 25 || partial {
+		t.Errorf("Expected summary of the whole day to be kept, got %v, %v, %v", midday, partial, err)
+	}
+	var summaries int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM daily_summaries WHERE city_id = $1`, first.CityID).Scan(&summaries); err != nil || summaries != 1 {
+		t.Errorf("Expected summary missing in the next run to be deleted, got %d, %v", summaries, err)
+	}
+
+	unknown := cities[1]
+	unknown.CityID = 4242
+	if err := bulk.ReplaceForecasts(ctx, []database.CityForecasts{unknown}); !errors.Is(err, database.ErrCityNotFound) {
+		t.Errorf("Expected ErrCityNotFound, got %v", err)
+	}
+}
+
+// benchmarkCities - number of cities saved in one benchmark iteration with 5 days each
+const benchmarkCities = 1000
+
+func BenchmarkCreateForecast(b *testing.B) {
+	ctx := context.Background()
+	repo := NewPostgresRepository(newTestClient(b))
+	forecasts := createCities(b, repo, benchmarkCities, 5)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range forecasts {
+			if err := repo.CreateForecast(ctx, &forecasts[j], forecasts[j].CityID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*len(forecasts))/b.Elapsed().Seconds(), "forecasts/s")
+}
+
+func BenchmarkReplaceForecasts(b *testing.B) {
+	ctx := context.Background()
+	repo := NewPostgresRepository(newTestClient(b))
+	bulk := repo.(database.BulkForecastWriter)
+	forecasts := createCities(b, repo, benchmarkCities, 5)
+	cities := byCity(forecasts)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := bulk.ReplaceForecasts(ctx, cities); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*len(forecasts))/b.Elapsed().Seconds(), "forecasts/s")
+}
@@ -0,0 +1,924 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// dateLayout - dates are stored as TEXT, timestamps as INTEGER unix microseconds
+const dateLayout = "2006-01-02"
+
+// SQLiteRepository implements Repository
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLiteRepository returns interface
+func NewSQLiteRepository(db *sql.DB) database.Repository {
+	return &SQLiteRepository{
+		db: db,
+	}
+}
+
+// formatQuery formats query string for better logging
+func formatQuery(q string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
+}
+
+// checkError converts foreign key violation to database.ErrCityNotFound
+func checkError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return database.ErrCityNotFound
+	}
+	return err
+}
+
+// notFound returns database.ErrCityNotFound if city does not exist, otherwise err
+func (r *SQLiteRepository) notFound(ctx context.Context, cityID int, err error) error {
+	q := `SELECT EXISTS (SELECT 1 FROM cities WHERE id = ?)`
+
+	var exists bool
+	if existsErr := r.db.QueryRowContext(ctx, q, cityID).Scan(&exists); existsErr != nil {
+		return existsErr
+	}
+	if !exists {
+		return database.ErrCityNotFound
+	}
+	return err
+}
+
+// toMicro converts time to stored timestamp
+func toMicro(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+// fromMicro converts stored timestamp to time in UTC
+func fromMicro(v int64) time.Time {
+	return time.UnixMicro(v).UTC()
+}
+
+// CreateCity creates a new city in database or refreshes coordinates of an existing one
+func (r *SQLiteRepository) CreateCity(ctx context.Context, city *models.City) error {
+	q := `
+	INSERT INTO cities
+	    (city, country, lat, long) VALUES (?, ?, ?, ?)
+		ON CONFLICT (city, country)
+		DO UPDATE SET lat = excluded.lat, long = excluded.long
+		RETURNING id
+	`
+	log.Println("SQL Query:", formatQuery(q), city.Name, city.Country, city.Latitude, city.Longitude)
+
+	return r.db.QueryRowContext(ctx, q, city.Name, city.Country, city.Latitude, city.Longitude).Scan(&city.ID)
+}
+
+// GetAllCities returns all cities from database sorted by city name
+func (r *SQLiteRepository) GetAllCities(ctx context.Context) ([]models.City, error) {
+	q := `SELECT id, city, country, lat, long FROM cities ORDER BY city, id`
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanCities(rows)
+}
+
+// ListCities returns a page of cities selected by filter, after the cursor in sort order
+func (r *SQLiteRepository) ListCities(ctx context.Context, filter database.CityFilter) ([]models.City, error) {
+	columns, values := filter.SortKey()
+	q := `
+		SELECT id, city, country, lat, long
+		FROM cities
+		WHERE (? = '' OR country = ?)`
+	args := []interface{}{filter.Country, filter.Country}
+	if filter.After != nil {
+		q += fmt.Sprintf("\n\t\tAND (%s) > (%s)", strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", "))
+		args = append(args, values...)
+	}
+	q += "\n\t\tORDER BY " + strings.Join(columns, ", ")
+	if filter.Limit > 0 {
+		q += "\n\t\tLIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	log.Println("SQL Query:", formatQuery(q), args)
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanCities(rows)
+}
+
+// scanCities reads cities selected as id, city, country, lat, long
+func scanCities(rows *sql.Rows) ([]models.City, error) {
+	cities := make([]models.City, 0)
+	for rows.Next() {
+		var city models.City
+		if err := rows.Scan(&city.ID, &city.Name, &city.Country, &city.Latitude, &city.Longitude); err != nil {
+			return nil, err
+		}
+		cities = append(cities, city)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cities, nil
+}
+
+// CreateForecast creates a new forecast in database for concrete city
+func (r *SQLiteRepository) CreateForecast(ctx context.Context, forecast *models.WeatherInfo, cityID int) error {
+	// temp is cast like INT column in PostgreSQL, fractional part is dropped
+	q := `INSERT INTO forecasts
+		(temp, date, additional_info, city_id)
+		VALUES (CAST(? AS INTEGER), ?, ?, ?)
+
+		ON CONFLICT (city_id, date)
+		DO UPDATE SET temp = excluded.temp, additional_info = excluded.additional_info
+    `
+
+	additionalInfo, err := json.Marshal(forecast.AdditionalInfo)
+	if err != nil {
+		return err
+	}
+
+	log.Println("SQL Query:", formatQuery(q), forecast.Temp, forecast.Date, cityID)
+	_, err = r.db.ExecContext(ctx, q, forecast.Temp, forecast.Date.Format(dateLayout), string(additionalInfo), cityID)
+	return checkError(err)
+}
+
+// ReplaceCityForecasts saves forecasts of one city and issued slots in one transaction,
+// days from the earliest new date on that are missing in forecasts are deleted. Nothing is changed on error
+func (r *SQLiteRepository) ReplaceCityForecasts(ctx context.Context, cityID int, forecasts []models.WeatherInfo, slots []models.ForecastSlot, summaries []models.DailySummary) error {
+	if len(forecasts) == 0 {
+		return nil
+	}
+
+	qForecast := `INSERT INTO forecasts
+		(temp, date, additional_info, city_id)
+		VALUES (CAST(? AS INTEGER), ?, ?, ?)
+
+		ON CONFLICT (city_id, date)
+		DO UPDATE SET temp = excluded.temp, additional_info = excluded.additional_info
+	`
+	qStale := `
+		DELETE FROM forecasts
+		WHERE city_id = ?
+		AND date >= ?
+		AND date NOT IN (SELECT value FROM json_each(?))
+	`
+	qSlot := `
+		INSERT INTO forecast_history
+			(city_id, provider, issued_at, valid_at, temp, pop, precipitation)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+
+		ON CONFLICT (city_id, provider, issued_at, valid_at)
+		DO UPDATE SET temp = excluded.temp, pop = excluded.pop, precipitation = excluded.precipitation
+	`
+
+	dates := make([]string, 0, len(forecasts))
+	from := forecasts[0].Date.Format(dateLayout)
+	for _, forecast := range forecasts {
+		date := forecast.Date.Format(dateLayout)
+		dates = append(dates, date)
+		if date < from {
+			from = date
+		}
+	}
+	datesJSON, err := json.Marshal(dates)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	log.Println("SQL Query:", formatQuery(qForecast), cityID, len(forecasts), "forecasts")
+	for _, forecast := range forecasts {
+		additionalInfo, err := json.Marshal(forecast.AdditionalInfo)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, qForecast, forecast.Temp, forecast.Date.Format(dateLayout), string(additionalInfo), cityID); err != nil {
+			return checkError(err)
+		}
+	}
+
+	log.Println("SQL Query:", formatQuery(qStale), cityID, from, dates)
+	if _, err := tx.ExecContext(ctx, qStale, cityID, from, string(datesJSON)); err != nil {
+		return err
+	}
+
+	log.Println("SQL Query:", formatQuery(qSlot), len(slots), "slots")
+	for _, slot := range slots {
+		if _, err := tx.ExecContext(ctx, qSlot, slot.CityID, slot.Provider, toMicro(slot.IssuedAt), toMicro(slot.ValidAt),
+			slot.Temp, slot.Pop, slot.Precipitation); err != nil {
+			return checkError(err)
+		}
+	}
+
+	if err := replaceSummaries(ctx, tx, cityID, summaries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceSummaries saves daily summaries ordered by date in transaction tx
+// and deletes summaries of the city from the first date on that are missing in summaries
+func replaceSummaries(ctx context.Context, tx *sql.Tx, cityID int, summaries []models.DailySummary) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	qSummary := `
+		INSERT INTO daily_summaries
+			(city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday, precipitation, wind_max, gust_max, pop_max, condition, icon, partial)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+
+		ON CONFLICT (city_id, date)
+		DO UPDATE SET utc_offset = excluded.utc_offset, temp_min = excluded.temp_min, temp_max = excluded.temp_max,
+			temp_mean = excluded.temp_mean, temp_midday = excluded.temp_midday, precipitation = excluded.precipitation,
+			wind_max = excluded.wind_max, gust_max = excluded.gust_max, pop_max = excluded.pop_max,
+			condition = excluded.condition, icon = excluded.icon, partial = excluded.partial
+		WHERE NOT excluded.partial OR daily_summaries.partial
+	`
+	qStale := `
+		DELETE FROM daily_summaries
+		WHERE city_id = ?
+		AND date >= ?
+		AND date NOT IN (SELECT value FROM json_each(?))
+	`
+
+	log.Println("SQL Query:", formatQuery(qSummary), len(summaries), "summaries")
+	dates := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		date := summary.Date.Format(dateLayout)
+		if _, err := tx.ExecContext(ctx, qSummary, cityID, date, summary.UTCOffset, summary.TempMin, summary.TempMax,
+			summary.TempMean, summary.TempMidday, summary.Precipitation, summary.WindMax, summary.GustMax, summary.PopMax,
+			summary.Condition, summary.Icon, summary.Partial); err != nil {
+			return checkError(err)
+		}
+		dates = append(dates, date)
+	}
+
+	datesJSON, err := json.Marshal(dates)
+	if err != nil {
+		return err
+	}
+	log.Println("SQL Query:", formatQuery(qStale), cityID, dates)
+	_, err = tx.ExecContext(ctx, qStale, cityID, dates[0], string(datesJSON))
+	return err
+}
+
+// GetShortForecastByCityID returns short forecast for concrete city from daily summaries of local today and later days,
+// average temperature is the mean of midday temperatures
+func (r *SQLiteRepository) GetShortForecastByCityID(ctx context.Context, cityID int) (*models.ShortForecast, error) {
+	q := `SELECT daily_summaries.temp_midday, daily_summaries.date, cities.city, cities.country
+		FROM daily_summaries
+		JOIN cities ON cities.id = daily_summaries.city_id
+		WHERE city_id = ?
+		AND date >= date(unixepoch() + utc_offset, 'unixepoch')
+		ORDER BY date
+	`
+
+	log.Println("SQL Query:", formatQuery(q), cityID)
+	rows, err := r.db.QueryContext(ctx, q, cityID)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var temp float64
+	var sumTemp float64
+	var count = 0
+
+	var date string
+	var city, country string
+	dateSlice := make([]time.Time, 0)
+
+	for rows.Next() {
+		if err := rows.Scan(&temp, &date, &city, &country); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		day, err := time.Parse(dateLayout, date)
+		if err != nil {
+			return nil, err
+		}
+		sumTemp += temp
+		count++
+		dateSlice = append(dateSlice, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if count == 0 {
+		return nil, r.notFound(ctx, cityID, database.ErrForecastNotFound)
+	}
+
+	return &models.ShortForecast{
+		City:     city,
+		Country:  country,
+		AvgTemp:  sumTemp / float64(count),
+		DateList: dateSlice,
+	}, nil
+}
+
+// GetDailySummaries returns daily summaries of concrete city from its local today on
+func (r *SQLiteRepository) GetDailySummaries(ctx context.Context, cityID int) ([]models.DailySummary, error) {
+	q := `SELECT city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday,
+			precipitation, wind_max, gust_max, pop_max, condition, icon, partial
+		FROM daily_summaries
+		WHERE city_id = ?
+		AND date >= date(unixepoch() + utc_offset, 'unixepoch')
+		ORDER BY date
+	`
+
+	log.Println("SQL Query:", formatQuery(q), cityID)
+	rows, err := r.db.QueryContext(ctx, q, cityID)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries, err := scanSummaries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, r.notFound(ctx, cityID, database.ErrForecastNotFound)
+	}
+	return summaries, nil
+}
+
+// GetCitiesDailySummaries returns daily summaries of cities from their local today on grouped by city
+func (r *SQLiteRepository) GetCitiesDailySummaries(ctx context.Context, cityIDs []int) (map[int][]models.DailySummary, error) {
+	q := `SELECT city_id, date, utc_offset, temp_min, temp_max, temp_mean, temp_midday,
+			precipitation, wind_max, gust_max, pop_max, condition, icon, partial
+		FROM daily_summaries
+		WHERE (? OR city_id IN (SELECT value FROM json_each(?)))
+		AND date >= date(unixepoch() + utc_offset, 'unixepoch')
+		ORDER BY city_id, date
+	`
+
+	ids, err := json.Marshal(cityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("SQL Query:", formatQuery(q), cityIDs)
+	rows, err := r.db.QueryContext(ctx, q, cityIDs == nil, string(ids))
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries, err := scanSummaries(rows)
+	if err != nil {
+		return nil, err
+	}
+	byCity := make(map[int][]models.DailySummary)
+	for _, summary := range summaries {
+		byCity[summary.CityID] = append(byCity[summary.CityID], summary)
+	}
+	return byCity, nil
+}
+
+// scanSummaries reads daily summaries selected in the column order of GetDailySummaries
+func scanSummaries(rows *sql.Rows) ([]models.DailySummary, error) {
+	summaries := make([]models.DailySummary, 0)
+	for rows.Next() {
+		var s models.DailySummary
+		var date string
+		if err := rows.Scan(&s.CityID, &date, &s.UTCOffset, &s.TempMin, &s.TempMax, &s.TempMean, &s.TempMidday,
+			&s.Precipitation, &s.WindMax, &s.GustMax, &s.PopMax, &s.Condition, &s.Icon, &s.Partial); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		var err error
+		if s.Date, err = time.Parse(dateLayout, date); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// GetForecastByCityIDandDate returns forecasts for concrete date
+func (r *SQLiteRepository) GetForecastByCityIDandDate(ctx context.Context, cityID int, date string) ([]models.WeatherInfo, error) {
+	q := `
+		SELECT id, temp, date, additional_info, city_id
+		FROM forecasts
+		WHERE city_id = ?
+		AND date = ?
+		ORDER BY date`
+
+	day, err := database.ParseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("SQL Query:", formatQuery(q), cityID, date)
+	rows, err := r.db.QueryContext(ctx, q, cityID, day.Format(dateLayout))
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	forecasts := make([]models.WeatherInfo, 0)
+	for rows.Next() {
+		var forecast models.WeatherInfo
+		var storedDate, additionalInfo string
+		if err := rows.Scan(&forecast.ID, &forecast.Temp, &storedDate, &additionalInfo, &forecast.CityID); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		if forecast.Date, err = time.Parse(dateLayout, storedDate); err != nil {
+			return nil, err
+		}
+		// unmarshal additional info includes forecasts for each 3 hours from database
+		if err := json.Unmarshal([]byte(additionalInfo), &forecast.AdditionalInfo); err != nil {
+			log.Println("Unmarshal error:", err, " additionalInfo:", additionalInfo)
+			return nil, err
+		}
+		forecasts = append(forecasts, forecast)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if len(forecasts) == 0 {
+		return nil, r.notFound(ctx, cityID, database.ErrForecastNotFound)
+	}
+
+	return forecasts, nil
+}
+
+// GetForecastByCityIDandDateTime returns forecast for concrete date and time
+func (r *SQLiteRepository) GetForecastByCityIDandDateTime(ctx context.Context, cityID int, date, time string) (*models.List, error) {
+	if err := database.ValidateClock(time); err != nil {
+		return nil, err
+	}
+	forecasts, err := r.GetForecastByCityIDandDate(ctx, cityID, date)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	// search for forecast for concrete time for concrete date
+	for _, forecast := range forecasts {
+		for _, info := range forecast.AdditionalInfo {
+			if info.DtTime.Format("15:04:05") == time {
+				return &info, nil
+			}
+		}
+	}
+	return nil, database.ErrForecastNotFound
+}
+
+// GetForecastRange returns 3-hour slots of concrete city valid in [from, to] ordered by time
+func (r *SQLiteRepository) GetForecastRange(ctx context.Context, cityID int, from, to time.Time) ([]models.List, error) {
+	q := `
+		SELECT slot.value
+		FROM forecasts, json_each(forecasts.additional_info) AS slot
+		WHERE city_id = ?
+		AND date BETWEEN ? AND ?
+		AND json_extract(slot.value, '$.dt') BETWEEN ? AND ?
+		ORDER BY json_extract(slot.value, '$.dt')`
+
+	log.Println("SQL Query:", formatQuery(q), cityID, from, to)
+	rows, err := r.db.QueryContext(ctx, q, cityID, from.UTC().Format(dateLayout), to.UTC().Format(dateLayout), from.Unix(), to.Unix())
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	slots := make([]models.List, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		var slot models.List
+		if err := json.Unmarshal([]byte(value), &slot); err != nil {
+			log.Println("Unmarshal error:", err, " slot:", value)
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if len(slots) == 0 {
+		if err := r.notFound(ctx, cityID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return slots, nil
+}
+
+// GetCitiesForecastRange returns 3-hour slots of cities valid in [from, to] grouped by city
+func (r *SQLiteRepository) GetCitiesForecastRange(ctx context.Context, cityIDs []int, from, to time.Time) (map[int][]models.List, error) {
+	q := `
+		SELECT city_id, slot.value
+		FROM forecasts, json_each(forecasts.additional_info) AS slot
+		WHERE (? OR city_id IN (SELECT value FROM json_each(?)))
+		AND date BETWEEN ? AND ?
+		AND json_extract(slot.value, '$.dt') BETWEEN ? AND ?
+		ORDER BY city_id, json_extract(slot.value, '$.dt')`
+
+	ids, err := json.Marshal(cityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("SQL Query:", formatQuery(q), cityIDs, from, to)
+	rows, err := r.db.QueryContext(ctx, q, cityIDs == nil, string(ids),
+		from.UTC().Format(dateLayout), to.UTC().Format(dateLayout), from.Unix(), to.Unix())
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	slots := make(map[int][]models.List)
+	for rows.Next() {
+		var cityID int
+		var value string
+		if err := rows.Scan(&cityID, &value); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		var slot models.List
+		if err := json.Unmarshal([]byte(value), &slot); err != nil {
+			log.Println("Unmarshal error:", err, " slot:", value)
+			return nil, err
+		}
+		slots[cityID] = append(slots[cityID], slot)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+
+	return slots, nil
+}
+
+// PurgeForecastSlots drops 3-hour slots from up to limit forecasts older than before, keeping the daily temperature
+func (r *SQLiteRepository) PurgeForecastSlots(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		UPDATE forecasts
+		SET additional_info = '[]'
+		WHERE id IN (
+			SELECT id FROM forecasts
+			WHERE date < ?
+			AND additional_info <> '[]'
+			LIMIT ?
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	return r.exec(ctx, q, before.Format(dateLayout), limit)
+}
+
+// PurgeForecasts deletes up to limit forecasts older than before
+func (r *SQLiteRepository) PurgeForecasts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		DELETE FROM forecasts
+		WHERE id IN (
+			SELECT id FROM forecasts
+			WHERE date < ?
+			LIMIT ?
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	return r.exec(ctx, q, before.Format(dateLayout), limit)
+}
+
+// PurgeDailySummaries deletes up to limit daily summaries older than before
+func (r *SQLiteRepository) PurgeDailySummaries(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		DELETE FROM daily_summaries
+		WHERE rowid IN (
+			SELECT rowid FROM daily_summaries
+			WHERE date < ?
+			LIMIT ?
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	return r.exec(ctx, q, before.Format(dateLayout), limit)
+}
+
+// exec executes statement and returns number of affected rows
+func (r *SQLiteRepository) exec(ctx context.Context, q string, args ...interface{}) (int64, error) {
+	result, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CreateObservation saves measured weather for concrete city, a repeated reading from the same source replaces the old one
+func (r *SQLiteRepository) CreateObservation(ctx context.Context, observation *models.Observation) error {
+	q := `
+		INSERT INTO observations
+			(city_id, observed_at, source, temp, humidity, pressure, wind_speed, wind_deg, clouds, precipitation, condition)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))
+
+		ON CONFLICT (city_id, observed_at, source)
+		DO UPDATE SET temp = excluded.temp, humidity = excluded.humidity, pressure = excluded.pressure,
+			wind_speed = excluded.wind_speed, wind_deg = excluded.wind_deg, clouds = excluded.clouds,
+			precipitation = excluded.precipitation, condition = excluded.condition
+		RETURNING id
+	`
+
+	log.Println("SQL Query:", formatQuery(q), observation.CityID, observation.ObservedAt, observation.Source)
+	err := r.db.QueryRowContext(ctx, q,
+		observation.CityID, toMicro(observation.ObservedAt), observation.Source, observation.Temp,
+		observation.Humidity, observation.Pressure, observation.WindSpeed, observation.WindDeg,
+		observation.Clouds, observation.Precipitation, observation.Condition,
+	).Scan(&observation.ID)
+	return checkError(err)
+}
+
+// GetObservations returns observations for concrete city in [from, to) ordered by time
+func (r *SQLiteRepository) GetObservations(ctx context.Context, cityID int, from, to time.Time) ([]models.Observation, error) {
+	q := `
+		SELECT id, city_id, observed_at, source, temp, humidity, pressure, wind_speed, wind_deg, clouds, precipitation, COALESCE(condition, '')
+		FROM observations
+		WHERE city_id = ?
+		AND observed_at >= ?
+		AND observed_at < ?
+		ORDER BY observed_at, source`
+
+	log.Println("SQL Query:", formatQuery(q), cityID, from, to)
+	rows, err := r.db.QueryContext(ctx, q, cityID, toMicro(from), toMicro(to))
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	observations := make([]models.Observation, 0)
+	for rows.Next() {
+		var o models.Observation
+		var observedAt int64
+		if err := rows.Scan(&o.ID, &o.CityID, &observedAt, &o.Source, &o.Temp, &o.Humidity, &o.Pressure,
+			&o.WindSpeed, &o.WindDeg, &o.Clouds, &o.Precipitation, &o.Condition); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		o.ObservedAt = fromMicro(observedAt)
+		observations = append(observations, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if len(observations) == 0 {
+		if err := r.notFound(ctx, cityID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return observations, nil
+}
+
+// CreateForecastSlots saves issued forecast slots, a repeated slot of the same run replaces the old one
+func (r *SQLiteRepository) CreateForecastSlots(ctx context.Context, slots []models.ForecastSlot) error {
+	q := `
+		INSERT INTO forecast_history
+			(city_id, provider, issued_at, valid_at, temp, pop, precipitation)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+
+		ON CONFLICT (city_id, provider, issued_at, valid_at)
+		DO UPDATE SET temp = excluded.temp, pop = excluded.pop, precipitation = excluded.precipitation
+	`
+
+	log.Println("SQL Query:", formatQuery(q), len(slots), "slots")
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, slot := range slots {
+		if _, err := stmt.ExecContext(ctx, slot.CityID, slot.Provider, toMicro(slot.IssuedAt), toMicro(slot.ValidAt),
+			slot.Temp, slot.Pop, slot.Precipitation); err != nil {
+			return checkError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// PurgeForecastHistory deletes up to limit issued forecast slots valid before before
+func (r *SQLiteRepository) PurgeForecastHistory(ctx context.Context, before time.Time, limit int) (int64, error) {
+	q := `
+		DELETE FROM forecast_history
+		WHERE id IN (
+			SELECT id FROM forecast_history
+			WHERE valid_at < ?
+			LIMIT ?
+		)`
+
+	log.Println("SQL Query:", formatQuery(q), before, limit)
+	return r.exec(ctx, q, toMicro(before), limit)
+}
+
+// GetVerificationPairs matches every issued forecast slot valid in [from, to) with the nearest observation within an hour,
+// pairs are ordered by city, valid time, issue time and provider
+func (r *SQLiteRepository) GetVerificationPairs(ctx context.Context, filter database.VerificationFilter) ([]models.VerificationPair, error) {
+	q := `
+		SELECT city_id, provider, issued_at, valid_at, temp, pop,
+			observed_at, observed_temp, precipitation, condition
+		FROM (
+			SELECT h.city_id, h.provider, h.issued_at, h.valid_at, h.temp, h.pop,
+				o.observed_at, o.temp AS observed_temp, o.precipitation, COALESCE(o.condition, '') AS condition,
+				ROW_NUMBER() OVER (PARTITION BY h.id ORDER BY ABS(o.observed_at - h.valid_at), o.source) AS nearest
+			FROM forecast_history h
+			JOIN observations o ON o.city_id = h.city_id
+			AND o.observed_at BETWEEN h.valid_at - 3600000000 AND h.valid_at + 3600000000
+			WHERE (? = 0 OR h.city_id = ?)
+			AND (? = '' OR h.provider = ?)
+			AND h.valid_at >= ?
+			AND h.valid_at < ?
+		)
+		WHERE nearest = 1
+		ORDER BY city_id, valid_at, issued_at, provider`
+
+	log.Println("SQL Query:", formatQuery(q), filter.CityID, filter.Provider, filter.From, filter.To)
+	rows, err := r.db.QueryContext(ctx, q, filter.CityID, filter.CityID, filter.Provider, filter.Provider,
+		toMicro(filter.From), toMicro(filter.To))
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	pairs := make([]models.VerificationPair, 0)
+	for rows.Next() {
+		var p models.VerificationPair
+		var issuedAt, validAt, observedAt int64
+		if err := rows.Scan(&p.CityID, &p.Provider, &issuedAt, &validAt, &p.ForecastTemp, &p.ForecastPop,
+			&observedAt, &p.ObservedTemp, &p.ObservedPrecipitation, &p.ObservedCondition); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		p.IssuedAt, p.ValidAt, p.ObservedAt = fromMicro(issuedAt), fromMicro(validAt), fromMicro(observedAt)
+		pairs = append(pairs, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+	if len(pairs) == 0 && filter.CityID != 0 {
+		if err := r.notFound(ctx, filter.CityID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return pairs, nil
+}
+
+// CreateBiasCorrection saves trained corrections as a new version and sets correction.Version
+func (r *SQLiteRepository) CreateBiasCorrection(ctx context.Context, correction *models.BiasCorrection) error {
+	q := `
+		INSERT INTO bias_corrections
+			(trained_at, window_from, window_to, min_samples)
+		VALUES (?, ?, ?, ?)
+		RETURNING version
+	`
+	qOffset := `
+		INSERT INTO bias_correction_offsets
+			(version, city_id, hour, lead_hours, bias, samples)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	log.Println("SQL Query:", formatQuery(q), correction.TrainedAt, correction.From, correction.To, correction.MinSamples)
+	if err := tx.QueryRowContext(ctx, q, toMicro(correction.TrainedAt), toMicro(correction.From), toMicro(correction.To),
+		correction.MinSamples).Scan(&correction.Version); err != nil {
+		return err
+	}
+
+	log.Println("SQL Query:", formatQuery(qOffset), len(correction.Offsets), "offsets")
+	stmt, err := tx.PrepareContext(ctx, qOffset)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, o := range correction.Offsets {
+		if _, err := stmt.ExecContext(ctx, correction.Version, o.CityID, o.Hour, o.LeadHours, o.Bias, o.Samples); err != nil {
+			return checkError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetBiasCorrection returns corrections of concrete version, version 0 means the latest one.
+// Returns nil if there is no such version
+func (r *SQLiteRepository) GetBiasCorrection(ctx context.Context, version int) (*models.BiasCorrection, error) {
+	q := `
+		SELECT version, trained_at, window_from, window_to, min_samples
+		FROM bias_corrections
+		WHERE ? = 0 OR version = ?
+		ORDER BY version DESC
+		LIMIT 1`
+	qOffset := `
+		SELECT city_id, hour, lead_hours, bias, samples
+		FROM bias_correction_offsets
+		WHERE version = ?
+		ORDER BY city_id, hour, lead_hours`
+
+	log.Println("SQL Query:", formatQuery(q), version)
+	var correction models.BiasCorrection
+	var trainedAt, from, to int64
+	err := r.db.QueryRowContext(ctx, q, version, version).Scan(&correction.Version, &trainedAt, &from, &to, &correction.MinSamples)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	correction.TrainedAt, correction.From, correction.To = fromMicro(trainedAt), fromMicro(from), fromMicro(to)
+
+	log.Println("SQL Query:", formatQuery(qOffset), correction.Version)
+	rows, err := r.db.QueryContext(ctx, qOffset, correction.Version)
+	if err != nil {
+		log.Println("Query error:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	correction.Offsets = make([]models.BiasOffset, 0)
+	for rows.Next() {
+		var o models.BiasOffset
+		if err := rows.Scan(&o.CityID, &o.Hour, &o.LeadHours, &o.Bias, &o.Samples); err != nil {
+			log.Println("Scan error:", err)
+			return nil, err
+		}
+		correction.Offsets = append(correction.Offsets, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error scanning row:", err)
+		return nil, err
+	}
+
+	return &correction, nil
+}
@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"weather_service/internal/config"
+	"weather_service/internal/database"
+	"weather_service/internal/database/databasetest"
+	"weather_service/pkg/client"
+)
+
+// TestMain runs tests from the repository root where migrate_sqlite.sql is
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	databasetest.TestRepository(t, func(t *testing.T) database.Repository {
+		var cfg config.Config
+		cfg.SQLite.Path = filepath.Join(t.TempDir(), "weather_service.db")
+
+		db, err := client.NewSQLiteClient(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return NewSQLiteRepository(db)
+	})
+}
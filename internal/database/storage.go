@@ -2,14 +2,86 @@ package database
 
 import (
 	"context"
+	"time"
 	"weather_service/internal/models"
 )
 
+// VerificationFilter selects forecast slots to verify, zero CityID and empty Provider match all
+type VerificationFilter struct {
+	CityID   int
+	Provider string
+	From     time.Time
+	To       time.Time
+}
+
+// PartitionManager is implemented by repositories that keep forecasts and issued forecast history in monthly partitions
+type PartitionManager interface {
+	// CreatePartitions makes sure partitions exist for every month from from to to inclusive
+	CreatePartitions(ctx context.Context, from, to time.Time) error
+	// DropForecastPartitions drops partitions of forecasts that only hold dates before before, returns their number
+	DropForecastPartitions(ctx context.Context, before time.Time) (int, error)
+	// DropForecastHistoryPartitions drops partitions of issued forecast slots valid before before, returns their number
+	DropForecastHistoryPartitions(ctx context.Context, before time.Time) (int, error)
+}
+
+// ForecastChange - forecast of a city for a date was saved or deleted
+type ForecastChange struct {
+	CityID int    `json:"city_id"`
+	Date   string `json:"date"`
+}
+
+// ChangeListener is implemented by repositories that report forecast changes made by all instances sharing the database
+type ChangeListener interface {
+	// ListenForecastChanges calls ready once changes are listened, if it is not nil,
+	// then handle for every change until ctx is done or the connection fails
+	ListenForecastChanges(ctx context.Context, ready func(), handle func(ForecastChange)) error
+}
+
+// CityForecasts - forecasts of one city with issued slots and daily summaries, saved together like ReplaceCityForecasts does
+type CityForecasts struct {
+	CityID    int
+	Forecasts []models.WeatherInfo
+	Slots     []models.ForecastSlot
+	Summaries []models.DailySummary
+}
+
+// BulkForecastWriter is implemented by repositories that save forecasts of many cities in one round trip
+type BulkForecastWriter interface {
+	// ReplaceForecasts saves every city like ReplaceCityForecasts in a single transaction, nothing is changed on error
+	ReplaceForecasts(ctx context.Context, cities []CityForecasts) error
+}
+
 type Repository interface {
 	CreateCity(ctx context.Context, city *models.City) error
 	GetAllCities(ctx context.Context) ([]models.City, error)
+	// ListCities returns a page of cities selected by filter, ordering and paging are done by the database
+	ListCities(ctx context.Context, filter CityFilter) ([]models.City, error)
 	CreateForecast(ctx context.Context, forecast *models.WeatherInfo, cityID int) error
+	// ReplaceCityForecasts saves forecasts of one city, issued slots and daily summaries in a single transaction:
+	// days from the earliest new date on are replaced, so on error the city keeps its previous days unchanged.
+	// A partial daily summary does not replace a stored summary of the whole day
+	ReplaceCityForecasts(ctx context.Context, cityID int, forecasts []models.WeatherInfo, slots []models.ForecastSlot, summaries []models.DailySummary) error
 	GetShortForecastByCityID(ctx context.Context, cityID int) (*models.ShortForecast, error)
+	// GetDailySummaries returns daily summaries of a city from its local today on, ordered by date
+	GetDailySummaries(ctx context.Context, cityID int) ([]models.DailySummary, error)
+	// GetCitiesDailySummaries returns daily summaries of many cities from their local today on in one query,
+	// grouped by city and ordered by date. nil cityIDs selects all cities, unknown ids are skipped
+	GetCitiesDailySummaries(ctx context.Context, cityIDs []int) (map[int][]models.DailySummary, error)
 	GetForecastByCityIDandDate(ctx context.Context, cityID int, datetime string) ([]models.WeatherInfo, error)
 	GetForecastByCityIDandDateTime(ctx context.Context, cityID int, date string, time string) (*models.List, error)
+	// GetForecastRange returns 3-hour slots of a city valid from from to to inclusive, ordered by time
+	GetForecastRange(ctx context.Context, cityID int, from, to time.Time) ([]models.List, error)
+	// GetCitiesForecastRange returns 3-hour slots valid from from to to inclusive of many cities in one query,
+	// grouped by city and ordered by time. nil cityIDs selects all cities, unknown ids are skipped
+	GetCitiesForecastRange(ctx context.Context, cityIDs []int, from, to time.Time) (map[int][]models.List, error)
+	PurgeForecastSlots(ctx context.Context, before time.Time, limit int) (int64, error)
+	PurgeForecasts(ctx context.Context, before time.Time, limit int) (int64, error)
+	PurgeDailySummaries(ctx context.Context, before time.Time, limit int) (int64, error)
+	CreateObservation(ctx context.Context, observation *models.Observation) error
+	GetObservations(ctx context.Context, cityID int, from, to time.Time) ([]models.Observation, error)
+	CreateForecastSlots(ctx context.Context, slots []models.ForecastSlot) error
+	PurgeForecastHistory(ctx context.Context, before time.Time, limit int) (int64, error)
+	GetVerificationPairs(ctx context.Context, filter VerificationFilter) ([]models.VerificationPair, error)
+	CreateBiasCorrection(ctx context.Context, correction *models.BiasCorrection) error
+	GetBiasCorrection(ctx context.Context, version int) (*models.BiasCorrection, error)
 }
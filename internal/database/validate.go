@@ -0,0 +1,34 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseDate parses date in YYYY-MM-DD format, returns ErrInvalidDate if it is not a valid calendar date
+func ParseDate(date string) (time.Time, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q, expected YYYY-MM-DD", ErrInvalidDate, date)
+	}
+	return day, nil
+}
+
+// ValidateClock checks time in 15:04:05 format, returns ErrInvalidDate if it is not a valid clock time
+func ValidateClock(clock string) error {
+	if _, err := time.Parse("15:04:05", clock); err != nil {
+		return fmt.Errorf("%w: time %q, expected HH:MM:SS", ErrInvalidDate, clock)
+	}
+	return nil
+}
+
+// ParseClock parses time of day in 15:04:05 or 15:04 format and returns it as offset from midnight,
+// returns ErrInvalidDate if it is not a valid clock time
+func ParseClock(clock string) (time.Duration, error) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, clock); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: time %q, expected HH:MM:SS or HH:MM", ErrInvalidDate, clock)
+}
@@ -128,3 +128,38 @@ func GetCityWeather(city *models.City, appid string) (*models.Forecast, error) {
 	}
 	return &forecast, nil
 }
+
+// GetCurrentWeather gets current city weather from OpenWeatherMap API
+func GetCurrentWeather(city *models.City, appid string) (*models.CurrentWeather, error) {
+	baseURL := "http://api.openweathermap.org/data/2.5/weather?"
+
+	params := url.Values{}
+	params.Add("lat", strconv.FormatFloat(city.Latitude, 'f', -1, 64))
+	params.Add("lon", strconv.FormatFloat(city.Longitude, 'f', -1, 64))
+	params.Add("appid", appid)
+
+	u, _ := url.ParseRequestURI(baseURL)
+	u.RawQuery = params.Encode()
+
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var current models.CurrentWeather
+	err = json.Unmarshal(body, &current)
+	if err != nil {
+		return nil, err
+	}
+	return &current, nil
+}
@@ -0,0 +1,103 @@
+package geocoding
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+)
+
+// ObservationUpdater - struct for saving current weather from OpenWeatherMap API as observations every {interval} seconds
+type ObservationUpdater struct {
+	apikey string
+	repo   database.Repository
+	ticker *time.Ticker
+}
+
+// NewObservationUpdater - constructor for ObservationUpdater struct
+func NewObservationUpdater(apikey string, repo database.Repository, interval time.Duration) *ObservationUpdater {
+	return &ObservationUpdater{
+		apikey: apikey,
+		repo:   repo,
+		ticker: time.NewTicker(interval),
+	}
+}
+
+// Start - starts observation updater in background with {interval}
+func (o *ObservationUpdater) Start() {
+	go func() {
+		defer o.ticker.Stop()
+		for {
+			select {
+			case <-o.ticker.C:
+				o.UpdateObservations()
+			}
+		}
+	}()
+}
+
+// UpdateObservations asynchronously saves current weather for all cities
+func (o *ObservationUpdater) UpdateObservations() {
+	ctx := context.Background()
+	cities, err := o.repo.GetAllCities(ctx)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	wg := sync.WaitGroup{}
+	for _, city := range cities {
+		wg.Add(1)
+		go func(city models.City) {
+			defer wg.Done()
+
+			current, err := GetCurrentWeather(&city, o.apikey)
+			if err != nil {
+				log.Println("Can not get current weather for city:", city.Name, "error", err)
+				return
+			}
+
+			observation := NewObservation(city.ID, current)
+			err = o.repo.CreateObservation(ctx, &observation)
+			if err != nil {
+				log.Println(err)
+			}
+		}(city)
+	}
+	//wait for all goroutines
+	wg.Wait()
+	log.Println("Observations updated")
+}
+
+// Stop - stops observation updater
+func (o *ObservationUpdater) Stop() {
+	o.ticker.Stop()
+}
+
+// NewObservation converts OpenWeatherMap current weather to observation in Celsius
+func NewObservation(cityID int, current *models.CurrentWeather) models.Observation {
+	humidity := current.Main.Humidity
+	pressure := current.Main.Pressure
+	windSpeed := current.Wind.Speed
+	windDeg := current.Wind.Deg
+	clouds := current.Clouds.All
+	precipitation := current.Rain.OneHour + current.Snow.OneHour
+
+	observation := models.Observation{
+		CityID:        cityID,
+		ObservedAt:    time.Unix(current.Dt, 0).UTC(),
+		Source:        models.SourceOpenWeatherMap,
+		Temp:          current.Main.Temp - 273,
+		Humidity:      &humidity,
+		Pressure:      &pressure,
+		WindSpeed:     &windSpeed,
+		WindDeg:       &windDeg,
+		Clouds:        &clouds,
+		Precipitation: &precipitation,
+	}
+	if len(current.Weather) > 0 {
+		observation.Condition = current.Weather[0].Main
+	}
+	return observation
+}
@@ -7,13 +7,25 @@ import (
 	"time"
 	"weather_service/internal/database"
 	"weather_service/internal/models"
+	"weather_service/internal/summary"
 )
 
+// SlotProcessor post-processes forecast slots of a city (in Celsius) before they are saved
+type SlotProcessor interface {
+	Process(cityID int, issuedAt time.Time, slots []models.List)
+}
+
+// bulkCities - number of cities saved at once when repository is a database.BulkForecastWriter
+const bulkCities = 500
+
 // WeatherUpdater - struct for updating weather data from OpenWeatherMap API every {interval} seconds
 type WeatherUpdater struct {
-	apikey string
-	repo   database.Repository
-	ticker *time.Ticker
+	apikey     string
+	repo       database.Repository
+	ticker     *time.Ticker
+	processors []SlotProcessor
+	// fetch gets forecast of a city, GetCityWeather unless replaced in tests
+	fetch func(city *models.City, appid string) (*models.Forecast, error)
 }
 
 // NewWeatherUpdater - constructor for WeatherUpdater struct
@@ -22,9 +34,15 @@ func NewWeatherUpdater(apikey string, repo database.Repository, interval time.Du
 		apikey: apikey,
 		repo:   repo,
 		ticker: time.NewTicker(interval),
+		fetch:  GetCityWeather,
 	}
 }
 
+// Use adds optional post-processing step, must be called before Start
+func (w *WeatherUpdater) Use(processor SlotProcessor) {
+	w.processors = append(w.processors, processor)
+}
+
 // Start - starts weather updater in background with {interval}
 func (w *WeatherUpdater) Start() {
 	go func() {
@@ -41,10 +59,16 @@ func (w *WeatherUpdater) Start() {
 // UpdateWeather asynchronously updates weather data from OpenWeatherMap API
 func (w *WeatherUpdater) UpdateWeather() {
 	ctx := context.Background()
+	//forecasts issued within the same hour are stored as one run
+	issuedAt := time.Now().UTC().Truncate(time.Hour)
 	cities, err := w.repo.GetAllCities(ctx)
 	if err != nil {
 		log.Println(err)
 	}
+	//with bulk writer forecasts of all cities are collected and saved in chunks after fetching
+	bulk, isBulk := w.repo.(database.BulkForecastWriter)
+	var mu sync.Mutex
+	fetched := make([]database.CityForecasts, 0, len(cities))
 	wg := sync.WaitGroup{}
 	for _, city := range cities {
 		wg.Add(1)
@@ -52,9 +76,10 @@ func (w *WeatherUpdater) UpdateWeather() {
 			defer wg.Done()
 			log.Println("Fetching weather for city:", city)
 
-			forecast, err := GetCityWeather(&city, w.apikey)
+			forecast, err := w.fetch(&city, w.apikey)
 			if err != nil {
 				log.Println(err)
+				return
 			}
 
 			//map for date and list of weather info for that date
@@ -66,7 +91,13 @@ func (w *WeatherUpdater) UpdateWeather() {
 				forecast.List[i].Main.FeelsLike -= 273
 				forecast.List[i].Main.TempMin -= 273
 				forecast.List[i].Main.TempMax -= 273
+			}
 
+			for _, processor := range w.processors {
+				processor.Process(city.ID, issuedAt, forecast.List)
+			}
+
+			for i := range forecast.List {
 				date := forecast.List[i].DtTime.Format("2006-01-02")
 				//creating map for date and list of weather info for that date
 				if _, ok := dateForecastMap[date]; !ok {
@@ -76,6 +107,21 @@ func (w *WeatherUpdater) UpdateWeather() {
 				dateForecastMap[date] = append(dateForecastMap[date], forecast.List[i])
 			}
 
+			//forecast as issued, it is saved for verification
+			slots := make([]models.ForecastSlot, 0, len(forecast.List))
+			for _, l := range forecast.List {
+				slots = append(slots, models.ForecastSlot{
+					CityID:        city.ID,
+					Provider:      models.SourceOpenWeatherMap,
+					IssuedAt:      issuedAt,
+					ValidAt:       l.DtTime,
+					Temp:          l.Main.Temp,
+					Pop:           l.Pop,
+					Precipitation: l.Precipitation3h(),
+				})
+			}
+
+			forecasts := make([]models.WeatherInfo, 0, len(dateForecastMap))
 			for _, fk := range dateForecastMap {
 				//creating weather info for that date
 				finalWI := models.WeatherInfo{}
@@ -100,19 +146,50 @@ func (w *WeatherUpdater) UpdateWeather() {
 				finalWI.CityID = city.ID
 				finalWI.Date = fk[0].DtTime
 
-				//saving weather info
-				err = w.repo.CreateForecast(ctx, &finalWI, city.ID)
-				if err != nil {
-					log.Println(err)
-				}
+				forecasts = append(forecasts, finalWI)
+			}
+
+			//aggregates local days of the city for short forecast, partial today and last day do not replace stored whole days
+			summaries := summary.Summarize(city.ID, forecast.List, forecast.City.Timezone)
+
+			if isBulk {
+				mu.Lock()
+				fetched = append(fetched, database.CityForecasts{CityID: city.ID, Forecasts: forecasts, Slots: slots, Summaries: summaries})
+				mu.Unlock()
+				return
+			}
+			//saving all days, issued slots and summaries of the city at once, on error the city keeps previous forecast
+			if err := w.repo.ReplaceCityForecasts(ctx, city.ID, forecasts, slots, summaries); err != nil {
+				log.Println("Can not save forecast for city:", city, "error", err)
 			}
 		}(city)
 	}
 	//wait for all goroutines
 	wg.Wait()
+	if isBulk {
+		w.replaceForecasts(ctx, bulk, fetched)
+	}
 	log.Println("Weather updated")
 }
 
+// replaceForecasts saves cities with bulk in chunks of bulkCities, a failed chunk is saved city by city,
+// so one city with an error does not keep the other cities of its chunk from being updated
+func (w *WeatherUpdater) replaceForecasts(ctx context.Context, bulk database.BulkForecastWriter, cities []database.CityForecasts) {
+	for start := 0; start < len(cities); start += bulkCities {
+		chunk := cities[start:min(start+bulkCities, len(cities))]
+		err := bulk.ReplaceForecasts(ctx, chunk)
+		if err == nil {
+			continue
+		}
+		log.Println("Can not save forecasts of", len(chunk), "cities at once, saving them one by one, error", err)
+		for _, city := range chunk {
+			if err := w.repo.ReplaceCityForecasts(ctx, city.CityID, city.Forecasts, city.Slots, city.Summaries); err != nil {
+				log.Println("Can not save forecast for city:", city.CityID, "error", err)
+			}
+		}
+	}
+}
+
 // Stop - stops weather updater
 func (w *WeatherUpdater) Stop() {
 	w.ticker.Stop()
@@ -0,0 +1,115 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/database/memory"
+	"weather_service/internal/models"
+)
+
+func TestUpdateWeatherKeepsPartialToday(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	city := models.City{Name: "Berlin", Country: "DE"}
+	if err := repo.CreateCity(ctx, &city); err != nil {
+		t.Fatal(err)
+	}
+
+	// the city is at local noon now, a run starts at the next 3-hour boundary and covers the rest of today only
+	now := time.Now().UTC()
+	utcOffset := (12 - now.Hour()) * 3600
+	start := now.Truncate(3 * time.Hour).Add(3 * time.Hour)
+	forecast := &models.Forecast{City: models.ForecastCity{Timezone: utcOffset}}
+	for i := 0; i < 40; i++ {
+		at := start.Add(time.Duration(i) * 3 * time.Hour)
+		forecast.List = append(forecast.List, models.List{Dt: int(at.Unix()), DtTime: at, Main: models.Main{Temp: 293}})
+	}
+
+	updater := NewWeatherUpdater("", repo, time.Hour)
+	defer updater.Stop()
+	updater.fetch = func(city *models.City, appid string) (*models.Forecast, error) {
+		return forecast, nil
+	}
+	updater.UpdateWeather()
+
+	local := now.Add(time.Duration(utcOffset) * time.Second)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	short, err := repo.GetShortForecastByCityID(ctx, city.ID)
+	if err != nil {
+		t.Fatalf("GetShortForecastByCityID: %v", err)
+	}
+	if len(short.DateList) == 0 || !short.DateList[0].Equal(today) {
+		t.Fatalf("Expected short forecast from local today %s, got %v", today, short.DateList)
+	}
+	if short.AvgTemp != 20 {
+		t.Errorf("Expected average temp 20, got %v", short.AvgTemp)
+	}
+
+	summaries, err := repo.GetDailySummaries(ctx, city.ID)
+	if err != nil {
+		t.Fatalf("GetDailySummaries: %v", err)
+	}
+	if !summaries[0].Partial || summaries[1].Partial {
+		t.Errorf("Expected only today to be partial, got %+v", summaries[:2])
+	}
+}
+
+// bulkRepo is memory repository saving cities one by one in ReplaceForecasts, or failing with err
+type bulkRepo struct {
+	database.Repository
+	err    error
+	chunks []int
+}
+
+func (r *bulkRepo) ReplaceForecasts(ctx context.Context, cities []database.CityForecasts) error {
+	r.chunks = append(r.chunks, len(cities))
+	if r.err != nil {
+		return r.err
+	}
+	for _, city := range cities {
+		if err := r.ReplaceCityForecasts(ctx, city.CityID, city.Forecasts, city.Slots, city.Summaries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestUpdateWeatherBulk(t *testing.T) {
+	// a failed chunk is saved city by city
+	for _, bulkErr := range []error{nil, errors.New("copy failed")} {
+		ctx := context.Background()
+		repo := &bulkRepo{Repository: memory.NewMemoryRepository(), err: bulkErr}
+		for _, name := range []string{"Berlin", "Paris"} {
+			city := models.City{Name: name, Country: "XX"}
+			if err := repo.CreateCity(ctx, &city); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		start := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+		updater := NewWeatherUpdater("", repo, time.Hour)
+		updater.fetch = func(city *models.City, appid string) (*models.Forecast, error) {
+			forecast := &models.Forecast{}
+			for i := 0; i < 8; i++ {
+				at := start.Add(time.Duration(i) * 3 * time.Hour)
+				forecast.List = append(forecast.List, models.List{Dt: int(at.Unix()), DtTime: at, Main: models.Main{Temp: 293}})
+			}
+			return forecast, nil
+		}
+		updater.UpdateWeather()
+		updater.Stop()
+
+		if len(repo.chunks) != 1 || repo.chunks[0] != 2 {
+			t.Errorf("bulk error %v: expected both cities in one chunk, got %v", bulkErr, repo.chunks)
+		}
+		cities, _ := repo.GetAllCities(ctx)
+		for _, city := range cities {
+			if _, err := repo.GetShortForecastByCityID(ctx, city.ID); err != nil {
+				t.Errorf("bulk error %v: expected forecast of %s to be saved, got %v", bulkErr, city.Name, err)
+			}
+		}
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"log"
 	"net/http"
 	"weather_service/internal/database"
-	"weather_service/pkg/utils"
+	"weather_service/internal/handlers"
 )
 
 const (
-	citiesPath = "/api/cities"
-	cityIDPath = "/api/cities/:id"
+	citiesPath   = "/api/cities"
+	cityIDPath   = "/api/cities/:id"
+	citiesV2Path = "/api/v2/cities"
 )
 
 type Handler struct {
@@ -24,25 +25,25 @@ func NewHandler(repo database.Repository) *Handler {
 
 }
 func (h *Handler) Register(router *httprouter.Router) {
-	router.HandlerFunc(http.MethodGet, citiesPath, h.GetAllCities)
+	router.HandlerFunc(http.MethodGet, citiesPath, handlers.Deprecated(citiesV2Path, h.GetAllCities))
+	router.HandlerFunc(http.MethodGet, citiesV2Path, h.GetAllCitiesV2)
 }
 
+// GetAllCities returns cities sorted by name by default, all of them unless limit is given
 func (h *Handler) GetAllCities(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	cities, err := h.repo.GetAllCities(r.Context())
+	// clients of v1 like static/cities.html expect the whole list
+	filter, err := parseCityFilter(r, 0)
 	if err != nil {
-		log.Println(err)
+		handlers.WriteBadRequest(w, r, err)
+		return
 	}
-
-	err = utils.WriteJSONIndented(w, cities)
+	cities, err := h.listCities(w, r, filter)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Error marshaling cities", http.StatusInternalServerError)
+		handlers.WriteError(w, r, err)
 		return
 	}
 
-	log.Println("Get all cities", cities)
+	handlers.Render(w, r, citiesV1(cities))
 
-	w.WriteHeader(http.StatusOK)
+	log.Println("Get all cities", cities)
 }
@@ -0,0 +1,84 @@
+package cities
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+)
+
+const (
+	// defaultPageLimit - cities in one page of v2 when limit is not given
+	defaultPageLimit = 100
+	// maxPageLimit - the largest page of cities
+	maxPageLimit = 1000
+)
+
+// parseCityFilter reads limit, cursor, sort and country query parameters, defaultLimit is used without limit
+// and 0 selects all cities
+func parseCityFilter(r *http.Request, defaultLimit int) (database.CityFilter, error) {
+	query := r.URL.Query()
+	filter := database.CityFilter{
+		Country: strings.ToUpper(query.Get("country")),
+		Sort:    query.Get("sort"),
+		Limit:   defaultLimit,
+	}
+	if filter.Sort == "" {
+		filter.Sort = database.CitySortName
+	}
+	if !database.ValidCitySort(filter.Sort) {
+		return filter, fmt.Errorf("invalid sort %q: expected name, country or lat", filter.Sort)
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return filter, fmt.Errorf("invalid limit %q: expected number from 1 to %d", value, maxPageLimit)
+		}
+		filter.Limit = limit
+	}
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := database.ParseCityCursor(value, filter.Sort)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+	return filter, nil
+}
+
+// listCities returns a page of cities selected by filter and sets Link header
+// with the first page and the next page if there is one, filter without limit selects the rest of cities
+func (h *Handler) listCities(w http.ResponseWriter, r *http.Request, filter database.CityFilter) ([]models.City, error) {
+	// one more city tells whether the next page exists
+	limit := filter.Limit
+	if limit > 0 {
+		filter.Limit++
+	}
+	cities, err := h.repo.ListCities(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"first\"", pageURL(r, "")))
+	if limit > 0 && len(cities) > limit {
+		cities = cities[:limit]
+		next := database.NewCityCursor(filter.Sort, cities[limit-1])
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"next\"", pageURL(r, next.Encode())))
+	}
+	return cities, nil
+}
+
+// pageURL returns path and query of the request with cursor replaced, empty cursor points at the first page
+func pageURL(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Del("cursor")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if len(query) == 0 {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + query.Encode()
+}
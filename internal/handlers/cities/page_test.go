@@ -0,0 +1,108 @@
+package cities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"weather_service/internal/database/memory"
+	"weather_service/internal/models"
+)
+
+// nextLink matches URL of the next page in Link header
+var nextLink = regexp.MustCompile(`<([^>]+)>; rel="next"`)
+
+func TestGetAllCitiesV2Pages(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	for _, name := range []string{"Berlin", "Hamburg", "Munich", "Cologne", "Paris"} {
+		country := "DE"
+		if name == "Paris" {
+			country = "FR"
+		}
+		if err := repo.CreateCity(context.Background(), &models.City{Name: name, Country: country}); err != nil {
+			t.Fatalf("CreateCity: %v", err)
+		}
+	}
+	h := NewHandler(repo)
+
+	var got, cursors []string
+	url := "/api/v2/cities?country=de&limit=3"
+	for pages := 0; url != ""; pages++ {
+		if pages == 3 {
+			t.Fatalf("Expected 2 pages, got more: %v", got)
+		}
+		w := httptest.NewRecorder()
+		h.GetAllCitiesV2(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", url, w.Code, w.Body.String())
+		}
+		var cities []cityV2
+		if err := json.Unmarshal(w.Body.Bytes(), &cities); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		for _, city := range cities {
+			got = append(got, city.Name)
+		}
+
+		links := strings.Join(w.Header().Values("Link"), ", ")
+		if !strings.Contains(links, `</api/v2/cities?country=de&limit=3>; rel="first"`) {
+			t.Errorf("Expected link to the first page, got %s", links)
+		}
+		url = ""
+		if m := nextLink.FindStringSubmatch(links); m != nil {
+			url = m[1]
+			next, err := neturl.Parse(url)
+			if err != nil {
+				t.Fatalf("Parse(%s): %v", url, err)
+			}
+			cursors = append(cursors, next.Query().Get("cursor"))
+		}
+	}
+	if strings.Join(got, ",") != "Berlin,Cologne,Hamburg,Munich" {
+		t.Errorf("Expected German cities by name, got %v", got)
+	}
+
+	// cursor of name order can not be used with another sort
+	cursor := cursors[0]
+	w := httptest.NewRecorder()
+	h.GetAllCitiesV2(w, httptest.NewRequest(http.MethodGet, "/api/v2/cities?sort=lat&cursor="+cursor, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for cursor of another sort, got %d", w.Code)
+	}
+}
+
+func TestGetAllCitiesWithoutLimit(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	for i := 0; i < defaultPageLimit+1; i++ {
+		if err := repo.CreateCity(context.Background(), &models.City{Name: fmt.Sprintf("City %03d", i), Country: "DE"}); err != nil {
+			t.Fatalf("CreateCity: %v", err)
+		}
+	}
+	h := NewHandler(repo)
+
+	// v1 without limit returns the whole list, v2 the first page
+	for url, expected := range map[string]int{"/api/cities": defaultPageLimit + 1, "/api/v2/cities": defaultPageLimit} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		if url == "/api/cities" {
+			h.GetAllCities(w, r)
+		} else {
+			h.GetAllCitiesV2(w, r)
+		}
+		var cities []json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &cities); err != nil {
+			t.Fatalf("%s: Unmarshal: %v", url, err)
+		}
+		if len(cities) != expected {
+			t.Errorf("%s: expected %d cities, got %d", url, expected, len(cities))
+		}
+		if next := nextLink.MatchString(strings.Join(w.Header().Values("Link"), ", ")); next != (url == "/api/v2/cities") {
+			t.Errorf("%s: unexpected next link %v", url, w.Header().Values("Link"))
+		}
+	}
+}
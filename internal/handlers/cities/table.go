@@ -0,0 +1,30 @@
+package cities
+
+import (
+	"weather_service/internal/models"
+	"weather_service/pkg/render"
+)
+
+// citiesV1 - cities in v1 schema with CSV form, one row per city
+type citiesV1 []models.City
+
+// Table returns one row per city
+func (c citiesV1) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(c))
+	for _, city := range c {
+		rows = append(rows, []string{render.Int(city.ID), city.Name, city.Country, render.Float(city.Latitude), render.Float(city.Longitude)})
+	}
+	return []string{"id", "name", "country", "lat", "lon"}, rows
+}
+
+// citiesV2 - cities in v2 schema with CSV form, one row per city
+type citiesV2 []cityV2
+
+// Table returns one row per city
+func (c citiesV2) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(c))
+	for _, city := range c {
+		rows = append(rows, []string{render.Int(city.ID), city.Name, city.Country, render.Float(city.Latitude), render.Float(city.Longitude)})
+	}
+	return []string{"id", "name", "country", "latitude", "longitude"}, rows
+}
@@ -0,0 +1,48 @@
+package cities
+
+import (
+	"net/http"
+	"weather_service/internal/handlers"
+	"weather_service/internal/models"
+)
+
+// cityV2 - city in v2 schema
+type cityV2 struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// newCityV2 converts city to v2 schema
+func newCityV2(city models.City) cityV2 {
+	return cityV2{
+		ID:        city.ID,
+		Name:      city.Name,
+		Country:   city.Country,
+		Latitude:  city.Latitude,
+		Longitude: city.Longitude,
+	}
+}
+
+// GetAllCitiesV2 returns a page of cities in v2 schema, sorted by name by default
+func (h *Handler) GetAllCitiesV2(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseCityFilter(r, defaultPageLimit)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+	cities, err := h.listCities(w, r, filter)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	response := make(citiesV2, 0, len(cities))
+	for _, city := range cities {
+		response = append(response, newCityV2(city))
+	}
+
+	handlers.Render(w, r, response)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// V1DeprecatedAt - date since v1 routes are deprecated in favour of /api/v2
+var V1DeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks responses of v1 route with Deprecation header (RFC 9745) and links successor route.
+// successor is a route pattern like /api/v2/cities/:id, its parameters are filled from the request
+func Deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", V1DeprecatedAt.Unix()))
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", expandPath(successor, r)))
+		next(w, r)
+	}
+}
+
+// expandPath replaces :name segments of route pattern with parameters of the request
+func expandPath(pattern string, r *http.Request) string {
+	params := httprouter.ParamsFromContext(r.Context())
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = params.ByName(segment[1:])
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"weather_service/internal/database"
+)
+
+// Error codes of Problem
+const (
+	CodeBadRequest       = "bad_request"
+	CodeCityNotFound     = "city_not_found"
+	CodeForecastNotFound = "forecast_not_found"
+	CodeInvalidDate      = "invalid_date"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeNotAcceptable    = "not_acceptable"
+	CodeInternal         = "internal_error"
+)
+
+// ProblemContentType - media type of error responses, see RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// Problem - RFC 7807 body of every error response. Code is a machine readable error code,
+// Detail is a human readable message and RequestID matches X-Request-ID header of the response
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// WriteError writes repository error with matching status code: unknown city or missing forecast is 404,
+// invalid date is 422, anything else is 500 and its details are only logged
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, database.ErrCityNotFound):
+		WriteProblem(w, r, http.StatusNotFound, CodeCityNotFound, err.Error())
+	case errors.Is(err, database.ErrForecastNotFound):
+		WriteProblem(w, r, http.StatusNotFound, CodeForecastNotFound, err.Error())
+	case errors.Is(err, database.ErrInvalidDate):
+		WriteProblem(w, r, http.StatusUnprocessableEntity, CodeInvalidDate, err.Error())
+	default:
+		log.Println("Request", RequestIDFromContext(r.Context()), "failed: error", err)
+		WriteProblem(w, r, http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// WriteBadRequest writes error of malformed request with status 400
+func WriteBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, http.StatusBadRequest, CodeBadRequest, err.Error())
+}
+
+// WriteProblem writes problem with status code, code and message, nothing must be written to w before
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    message,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: RequestIDFromContext(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Println(err)
+	}
+}
+
+// NotFound writes problem for unknown route, it is used as NotFound handler of the router
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, http.StatusNotFound, CodeNotFound, "no route for "+r.URL.Path)
+}
+
+// MethodNotAllowed writes problem for known route requested with unsupported method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed for "+r.URL.Path)
+}
+
+// Panic writes internal error problem for recovered panic of a handler
+func Panic(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+	log.Println("Request", RequestIDFromContext(r.Context()), "panicked:", recovered)
+	WriteProblem(w, r, http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weather_service/internal/database"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{database.ErrCityNotFound, http.StatusNotFound, CodeCityNotFound},
+		{database.ErrForecastNotFound, http.StatusNotFound, CodeForecastNotFound},
+		{fmt.Errorf("%w: %q", database.ErrInvalidDate, "2024-13-45"), http.StatusUnprocessableEntity, CodeInvalidDate},
+		{errors.New("connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/cities/1/forecasts/shortforecast/", nil)
+		RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			WriteError(w, r, tt.err)
+		})).ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%v: expected status %d, got %d", tt.err, tt.status, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != ProblemContentType {
+			t.Errorf("%v: expected %s, got %s", tt.err, ProblemContentType, contentType)
+		}
+		var problem Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%v: invalid body %q: %v", tt.err, w.Body.String(), err)
+		}
+		if problem.Code != tt.code || problem.Status != tt.status || problem.Detail == "" || problem.Title == "" {
+			t.Errorf("%v: expected code %s with message, got %+v", tt.err, tt.code, problem)
+		}
+		if problem.RequestID == "" || problem.RequestID != w.Header().Get(RequestIDHeader) {
+			t.Errorf("%v: expected request id %q in body, got %q", tt.err, w.Header().Get(RequestIDHeader), problem.RequestID)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		sent string
+		keep bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"has space", false},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		var seen string
+		handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = RequestIDFromContext(r.Context())
+		}))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/cities", nil)
+		r.Header.Set(RequestIDHeader, tt.sent)
+		handler.ServeHTTP(w, r)
+
+		got := w.Header().Get(RequestIDHeader)
+		if got == "" || got != seen {
+			t.Errorf("%q: expected the same id in context and header, got %q and %q", tt.sent, seen, got)
+		}
+		if (got == tt.sent) != tt.keep {
+			t.Errorf("%q: expected kept %v, got id %q", tt.sent, tt.keep, got)
+		}
+	}
+}
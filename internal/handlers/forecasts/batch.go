@@ -0,0 +1,186 @@
+package forecasts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/handlers"
+	"weather_service/internal/interpolation"
+	"weather_service/internal/models"
+)
+
+// maxBatchCities - maximum number of city ids listed in one batch request
+const maxBatchCities = 100
+
+// batchForecastV2 - forecasts of many cities in v2 schema: daily summaries without date,
+// slots of the date with date, one slot with date and time
+type batchForecastV2 struct {
+	Date   string        `json:"date,omitempty"`
+	Time   string        `json:"time,omitempty"`
+	Cities []cityBatchV2 `json:"cities"`
+}
+
+// cityBatchV2 - forecast of one city in batch, days, slots and slot are omitted when the city has no forecast for them
+type cityBatchV2 struct {
+	CityID  int            `json:"city_id"`
+	Name    string         `json:"name"`
+	Country string         `json:"country"`
+	Days    []daySummaryV2 `json:"days,omitempty"`
+	Slots   []slotV2       `json:"slots,omitempty"`
+	Slot    *slotV2        `json:"slot,omitempty"`
+}
+
+// parseCityIDs parses comma separated city ids, all or empty value select all cities and return nil
+func parseCityIDs(value string) ([]int, error) {
+	if value == "" || value == "all" {
+		return nil, nil
+	}
+	seen := make(map[int]bool)
+	cityIDs := make([]int, 0)
+	for _, part := range strings.Split(value, ",") {
+		cityID, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || cityID < 1 {
+			return nil, fmt.Errorf("invalid city id %q: expected comma separated ids or all", part)
+		}
+		if !seen[cityID] {
+			seen[cityID] = true
+			cityIDs = append(cityIDs, cityID)
+		}
+	}
+	if len(cityIDs) > maxBatchCities {
+		return nil, fmt.Errorf("too many city ids: %d, at most %d are allowed", len(cityIDs), maxBatchCities)
+	}
+	return cityIDs, nil
+}
+
+// GetBatchForecastV2 returns forecasts of listed or all cities in one response: daily summaries by default,
+// 3-hour slots of date or slot at date and time interpolated like GetForecastByDateTimeV2
+func (h Handler) GetBatchForecastV2(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cityIDs, err := parseCityIDs(query.Get("city_ids"))
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+	date, clock := query.Get("date"), query.Get("time")
+	if clock != "" && date == "" {
+		handlers.WriteBadRequest(w, r, errors.New("time requires date"))
+		return
+	}
+
+	cities, err := h.selectCities(r, cityIDs)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	response := batchForecastV2{Date: date, Time: clock, Cities: make([]cityBatchV2, 0, len(cities))}
+	for _, city := range cities {
+		response.Cities = append(response.Cities, cityBatchV2{CityID: city.ID, Name: city.Name, Country: city.Country})
+	}
+
+	switch {
+	case date == "":
+		err = h.batchSummaries(r, cityIDs, response.Cities)
+	case clock == "":
+		err = h.batchDay(r, cityIDs, date, response.Cities)
+	default:
+		err = h.batchSlot(r, cityIDs, date, clock, response.Cities)
+	}
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	handlers.Render(w, r, response)
+}
+
+// selectCities returns cities in order of cityIDs or all cities sorted by name for nil cityIDs,
+// database.ErrCityNotFound names the first unknown id
+func (h Handler) selectCities(r *http.Request, cityIDs []int) ([]models.City, error) {
+	all, err := h.repo.GetAllCities(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	if cityIDs == nil {
+		return all, nil
+	}
+
+	byID := make(map[int]models.City, len(all))
+	for _, city := range all {
+		byID[city.ID] = city
+	}
+	cities := make([]models.City, 0, len(cityIDs))
+	for _, cityID := range cityIDs {
+		city, ok := byID[cityID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", database.ErrCityNotFound, cityID)
+		}
+		cities = append(cities, city)
+	}
+	return cities, nil
+}
+
+// batchSummaries fills daily summaries of cities from their local today on
+func (h Handler) batchSummaries(r *http.Request, cityIDs []int, cities []cityBatchV2) error {
+	summaries, err := h.repo.GetCitiesDailySummaries(r.Context(), cityIDs)
+	if err != nil {
+		return err
+	}
+	for i := range cities {
+		cities[i].Days = make([]daySummaryV2, 0, len(summaries[cities[i].CityID]))
+		for _, day := range summaries[cities[i].CityID] {
+			cities[i].Days = append(cities[i].Days, newDaySummaryV2(day))
+		}
+	}
+	return nil
+}
+
+// batchDay fills 3-hour slots of cities for date
+func (h Handler) batchDay(r *http.Request, cityIDs []int, date string, cities []cityBatchV2) error {
+	day, err := database.ParseDate(date)
+	if err != nil {
+		return err
+	}
+	slots, err := h.repo.GetCitiesForecastRange(r.Context(), cityIDs, day, day.Add(24*time.Hour-time.Second))
+	if err != nil {
+		return err
+	}
+	for i := range cities {
+		cities[i].Slots = make([]slotV2, 0, len(slots[cities[i].CityID]))
+		for _, l := range slots[cities[i].CityID] {
+			cities[i].Slots = append(cities[i].Slots, newSlotV2(l))
+		}
+	}
+	return nil
+}
+
+// batchSlot fills slot of cities at date and time, interpolated between the surrounding 3-hour slots
+func (h Handler) batchSlot(r *http.Request, cityIDs []int, date, clock string, cities []cityBatchV2) error {
+	day, err := database.ParseDate(date)
+	if err != nil {
+		return err
+	}
+	offset, err := database.ParseClock(clock)
+	if err != nil {
+		return err
+	}
+	at := day.Add(offset)
+
+	// surrounding slots are at most 3 hours away
+	slots, err := h.repo.GetCitiesForecastRange(r.Context(), cityIDs, at.Add(-3*time.Hour), at.Add(3*time.Hour))
+	if err != nil {
+		return err
+	}
+	for i := range cities {
+		if l, ok := interpolation.At(slots[cities[i].CityID], at); ok {
+			slot := newSlotV2(l)
+			cities[i].Slot = &slot
+		}
+	}
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 	"weather_service/internal/database"
+	"weather_service/internal/handlers"
+	"weather_service/internal/interpolation"
 	"weather_service/pkg/utils"
 )
 
@@ -13,6 +16,16 @@ const (
 	shortForecastPath        = "/api/cities/:id/forecasts/shortforecast/"
 	forecastPathWithDate     = "/api/cities/:id/forecasts/fullforecast/:date/"
 	forecastPathWithDateTime = "/api/cities/:id/forecasts/fullforecast/:date/:time/"
+	forecastRangePath        = "/api/cities/:id/forecasts"
+
+	summaryV2Path              = "/api/v2/cities/:id/summary"
+	forecastV2PathWithDate     = "/api/v2/cities/:id/forecasts/:date"
+	forecastV2PathWithDateTime = "/api/v2/cities/:id/forecasts/:date/:time"
+	forecastRangeV2Path        = "/api/v2/cities/:id/forecasts"
+	batchV2Path                = "/api/v2/forecasts"
+
+	// defaultRangeSpan - length of time range when to is not given
+	defaultRangeSpan = 24 * time.Hour
 )
 
 type Handler struct {
@@ -26,99 +39,110 @@ func NewHandler(repo database.Repository) *Handler {
 }
 
 func (h *Handler) Register(router *httprouter.Router) {
-	router.HandlerFunc(http.MethodGet, shortForecastPath, h.GetShortForecastByCityID)
-	router.HandlerFunc(http.MethodGet, forecastPathWithDate, h.GetForecastByCityIDandDate)
-	router.HandlerFunc(http.MethodGet, forecastPathWithDateTime, h.GetForecastByCityIDandDateTime)
+	router.HandlerFunc(http.MethodGet, shortForecastPath, handlers.Deprecated(summaryV2Path, h.GetShortForecastByCityID))
+	router.HandlerFunc(http.MethodGet, forecastPathWithDate, handlers.Deprecated(forecastV2PathWithDate, h.GetForecastByCityIDandDate))
+	router.HandlerFunc(http.MethodGet, forecastPathWithDateTime, handlers.Deprecated(forecastV2PathWithDateTime, h.GetForecastByCityIDandDateTime))
+	router.HandlerFunc(http.MethodGet, forecastRangePath, h.GetForecastRange)
+
+	router.HandlerFunc(http.MethodGet, summaryV2Path, h.GetSummaryV2)
+	router.HandlerFunc(http.MethodGet, forecastV2PathWithDate, h.GetForecastByDateV2)
+	router.HandlerFunc(http.MethodGet, forecastV2PathWithDateTime, h.GetForecastByDateTimeV2)
+	router.HandlerFunc(http.MethodGet, forecastRangeV2Path, h.GetForecastRangeV2)
+	router.HandlerFunc(http.MethodGet, batchV2Path, h.GetBatchForecastV2)
 }
 
+// GetShortForecastByCityID returns short forecast for concrete city
 func (h Handler) GetShortForecastByCityID(w http.ResponseWriter, r *http.Request) {
-	{
-		w.Header().Set("Content-Type", "application/json")
-		//w.Write([]byte("Forecast:\n"))
-
-		params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
-
-		cityIDString := params.ByName("id")
-		cityID, err := strconv.Atoi(cityIDString)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		}
-
-		forecasts, err := h.repo.GetShortForecastByCityID(r.Context(), cityID)
-		if err != nil {
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-
-		err = utils.WriteJSONIndented(w, forecasts)
-		if err != nil {
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-
-		log.Println("Get short forecast", forecasts)
-		w.WriteHeader(http.StatusOK)
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	cityID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	forecasts, err := h.repo.GetShortForecastByCityID(r.Context(), cityID)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
 	}
+
+	handlers.Render(w, r, (*shortForecastV1)(forecasts))
+
+	log.Println("Get short forecast", forecasts)
 }
 
 // GetForecastByCityIDandDate returns forecast for concrete date
 func (h Handler) GetForecastByCityIDandDate(w http.ResponseWriter, r *http.Request) {
-	{
-		w.Header().Set("Content-Type", "application/json")
-		params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
-
-		cityIDString := params.ByName("id")
-		cityID, err := strconv.Atoi(cityIDString)
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
 
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		}
+	cityID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
 
-		date := params.ByName("date")
+	date := params.ByName("date")
 
-		forecasts, err := h.repo.GetForecastByCityIDandDate(r.Context(), cityID, date)
-		if err != nil {
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	forecasts, err := h.repo.GetForecastByCityIDandDate(r.Context(), cityID, date)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
 
-		err = utils.WriteJSONIndented(w, forecasts)
-		if err != nil {
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	handlers.Render(w, r, dayForecastsV1(forecasts))
 
-		log.Println("Get forecast for concrete date", forecasts)
-		w.WriteHeader(http.StatusOK)
-	}
+	log.Println("Get forecast for concrete date", forecasts)
 }
 
-// GetForecastByCityIDandDateTime returns forecast for concrete date and time
+// GetForecastByCityIDandDateTime returns forecast for concrete date and time, interpolated between 3-hour slots
 func (h Handler) GetForecastByCityIDandDateTime(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
 
-	cityIDString := params.ByName("id")
-	cityID, err := strconv.Atoi(cityIDString)
+	cityID, err := strconv.Atoi(params.ByName("id"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		handlers.WriteBadRequest(w, r, err)
+		return
 	}
 
 	date := params.ByName("date")
 	time := params.ByName("time")
 
-	forecasts, err := h.repo.GetForecastByCityIDandDateTime(r.Context(), cityID, date, time)
+	forecasts, err := interpolation.ForecastAt(r.Context(), h.repo, cityID, date, time)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	handlers.Render(w, r, (*slotV1)(forecasts))
+
+	log.Println("Get forecast for concrete date and time", forecasts)
+}
+
+// GetForecastRange returns 3-hour forecasts for concrete city valid in time range across days, next 24 hours by default
+func (h Handler) GetForecastRange(w http.ResponseWriter, r *http.Request) {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	cityID, err := strconv.Atoi(params.ByName("id"))
 	if err != nil {
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handlers.WriteBadRequest(w, r, err)
+		return
 	}
 
-	err = utils.WriteJSONIndented(w, forecasts)
+	query := r.URL.Query()
+	from, to, err := utils.ParseUpcomingRange(query.Get("from"), query.Get("to"), defaultRangeSpan)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handlers.WriteBadRequest(w, r, err)
+		return
 	}
 
-	log.Println("Get forecast for concrete date and time", forecasts)
-	w.WriteHeader(http.StatusOK)
+	forecasts, err := h.repo.GetForecastRange(r.Context(), cityID, from, to)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	handlers.Render(w, r, slotsV1(forecasts))
+
+	log.Println("Get forecast for time range", from, to, forecasts)
 }
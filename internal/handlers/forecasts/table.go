@@ -0,0 +1,149 @@
+package forecasts
+
+import (
+	"strconv"
+	"weather_service/internal/models"
+	"weather_service/pkg/render"
+)
+
+// slotColumns - CSV columns of a 3-hour slot, corrected_temp is empty without bias correction
+var slotColumns = []string{
+	"time", "temp", "feels_like", "temp_min", "temp_max", "pressure", "humidity", "clouds", "visibility",
+	"wind_speed", "wind_deg", "wind_gust", "pop", "precipitation", "condition", "description", "icon",
+	"corrected_temp", "interpolated",
+}
+
+// daySummaryColumns - CSV columns of a daily summary
+var daySummaryColumns = []string{
+	"date", "temp_min", "temp_max", "temp", "condition", "icon", "precipitation", "pop_max", "wind_max", "gust_max",
+	"partial",
+}
+
+// row returns values of slot in order of slotColumns
+func (s slotV2) row() []string {
+	var correctedTemp string
+	if s.Correction != nil {
+		correctedTemp = render.Float(s.Correction.Temp)
+	}
+	return []string{
+		render.Time(s.Time), render.Float(s.Temp), render.Float(s.FeelsLike), render.Float(s.TempMin),
+		render.Float(s.TempMax), render.Int(s.Pressure), render.Int(s.Humidity), render.Int(s.Clouds),
+		render.Int(s.Visibility), render.Float(s.WindSpeed), render.Int(s.WindDeg), render.Float(s.WindGust),
+		render.Float(s.Pop), render.Float(s.Precipitation), s.Condition, s.Description, s.Icon,
+		correctedTemp, strconv.FormatBool(s.Interpolated),
+	}
+}
+
+// row returns values of daily summary in order of daySummaryColumns
+func (d daySummaryV2) row() []string {
+	return []string{
+		d.Date, render.Float(d.TempMin), render.Float(d.TempMax), render.Float(d.Temp), d.Condition, d.Icon,
+		render.Float(d.Precipitation), render.Float(d.PopMax), render.Float(d.WindMax), render.Float(d.GustMax),
+		strconv.FormatBool(d.Partial),
+	}
+}
+
+// Table returns one row per slot
+func (s slotV2) Table() ([]string, [][]string) {
+	return slotColumns, [][]string{s.row()}
+}
+
+// Table returns one row per local day
+func (s summaryV2) Table() ([]string, [][]string) {
+	header := append([]string{"city_id", "utc_offset"}, daySummaryColumns...)
+	rows := make([][]string, 0, len(s.Days))
+	for _, day := range s.Days {
+		rows = append(rows, append([]string{render.Int(s.CityID), render.Int(s.UTCOffset)}, day.row()...))
+	}
+	return header, rows
+}
+
+// Table returns one row per slot
+func (f dayForecastV2) Table() ([]string, [][]string) {
+	header := append([]string{"city_id", "date"}, slotColumns...)
+	rows := make([][]string, 0, len(f.Slots))
+	for _, slot := range f.Slots {
+		rows = append(rows, append([]string{render.Int(f.CityID), f.Date}, slot.row()...))
+	}
+	return header, rows
+}
+
+// Table returns one row per slot
+func (f rangeForecastV2) Table() ([]string, [][]string) {
+	header := append([]string{"city_id"}, slotColumns...)
+	rows := make([][]string, 0, len(f.Slots))
+	for _, slot := range f.Slots {
+		rows = append(rows, append([]string{render.Int(f.CityID)}, slot.row()...))
+	}
+	return header, rows
+}
+
+// Table returns one row per city and slot, or per city and day for daily summaries
+func (f batchForecastV2) Table() ([]string, [][]string) {
+	columns := slotColumns
+	if f.Date == "" {
+		columns = daySummaryColumns
+	}
+	header := append([]string{"city_id", "name", "country"}, columns...)
+	rows := make([][]string, 0)
+	for _, city := range f.Cities {
+		prefix := []string{render.Int(city.CityID), city.Name, city.Country}
+		for _, day := range city.Days {
+			rows = append(rows, append(append([]string{}, prefix...), day.row()...))
+		}
+		for _, slot := range city.Slots {
+			rows = append(rows, append(append([]string{}, prefix...), slot.row()...))
+		}
+		if city.Slot != nil {
+			rows = append(rows, append(append([]string{}, prefix...), city.Slot.row()...))
+		}
+	}
+	return header, rows
+}
+
+// shortForecastV1 - short forecast of v1 with CSV form, one row per forecast date
+type shortForecastV1 models.ShortForecast
+
+// Table returns one row per forecast date
+func (f *shortForecastV1) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(f.DateList))
+	for _, date := range f.DateList {
+		rows = append(rows, []string{f.Country, f.City, render.Float(f.AvgTemp), render.Time(date)})
+	}
+	return []string{"country", "city", "avg_temp", "date"}, rows
+}
+
+// dayForecastsV1 - forecasts of a date in v1 schema with CSV form, one row per 3-hour slot
+type dayForecastsV1 []models.WeatherInfo
+
+// Table returns one row per slot in columns of v2 slots
+func (f dayForecastsV1) Table() ([]string, [][]string) {
+	header := append([]string{"city_id", "date"}, slotColumns...)
+	rows := make([][]string, 0)
+	for _, forecast := range f {
+		for _, l := range forecast.AdditionalInfo {
+			rows = append(rows, append([]string{render.Int(forecast.CityID), forecast.Date.Format("2006-01-02")}, newSlotV2(l).row()...))
+		}
+	}
+	return header, rows
+}
+
+// slotsV1 - 3-hour forecasts in v1 schema with CSV form in columns of v2 slots
+type slotsV1 []models.List
+
+// Table returns one row per slot
+func (s slotsV1) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(s))
+	for _, l := range s {
+		rows = append(rows, newSlotV2(l).row())
+	}
+	return slotColumns, rows
+}
+
+// slotV1 - 3-hour forecast in v1 schema with CSV form
+type slotV1 models.List
+
+// Table returns one row in columns of v2 slots
+func (s *slotV1) Table() ([]string, [][]string) {
+	return slotColumns, [][]string{newSlotV2(models.List(*s)).row()}
+}
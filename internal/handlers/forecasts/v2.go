@@ -0,0 +1,244 @@
+package forecasts
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/handlers"
+	"weather_service/internal/interpolation"
+	"weather_service/internal/models"
+	"weather_service/pkg/utils"
+)
+
+// summaryV2 - short forecast in v2 schema, one entry per local calendar day of the city from today on
+type summaryV2 struct {
+	CityID int `json:"city_id"`
+	// UTCOffset - offset of city local time from UTC in seconds
+	UTCOffset int            `json:"utc_offset"`
+	Days      []daySummaryV2 `json:"days"`
+}
+
+// daySummaryV2 - forecast of one local day aggregated from 3-hour slots, temp is the temperature nearest to noon
+type daySummaryV2 struct {
+	Date          string  `json:"date"`
+	TempMin       float64 `json:"temp_min"`
+	TempMax       float64 `json:"temp_max"`
+	Temp          float64 `json:"temp"`
+	Condition     string  `json:"condition"`
+	Icon          string  `json:"icon"`
+	Precipitation float64 `json:"precipitation"`
+	PopMax        float64 `json:"pop_max"`
+	WindMax       float64 `json:"wind_max"`
+	GustMax       float64 `json:"gust_max"`
+	Partial       bool    `json:"partial"`
+}
+
+// newDaySummaryV2 converts daily summary to v2 schema
+func newDaySummaryV2(day models.DailySummary) daySummaryV2 {
+	return daySummaryV2{
+		Date:          day.Date.Format("2006-01-02"),
+		TempMin:       day.TempMin,
+		TempMax:       day.TempMax,
+		Temp:          day.TempMidday,
+		Condition:     day.Condition,
+		Icon:          day.Icon,
+		Precipitation: day.Precipitation,
+		PopMax:        day.PopMax,
+		WindMax:       day.WindMax,
+		GustMax:       day.GustMax,
+		Partial:       day.Partial,
+	}
+}
+
+// dayForecastV2 - forecast of one day in v2 schema
+type dayForecastV2 struct {
+	CityID int      `json:"city_id"`
+	Date   string   `json:"date"`
+	Temp   float64  `json:"temp"`
+	Slots  []slotV2 `json:"slots"`
+}
+
+// rangeForecastV2 - 3-hour forecasts valid in time range in v2 schema
+type rangeForecastV2 struct {
+	CityID int       `json:"city_id"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Slots  []slotV2  `json:"slots"`
+}
+
+// slotV2 - 3-hour forecast in v2 schema, temperatures are in Celsius, wind in m/s and precipitation in mm
+type slotV2 struct {
+	Time          time.Time     `json:"time"`
+	Temp          float64       `json:"temp"`
+	FeelsLike     float64       `json:"feels_like"`
+	TempMin       float64       `json:"temp_min"`
+	TempMax       float64       `json:"temp_max"`
+	Pressure      int           `json:"pressure"`
+	Humidity      int           `json:"humidity"`
+	Clouds        int           `json:"clouds"`
+	Visibility    int           `json:"visibility"`
+	WindSpeed     float64       `json:"wind_speed"`
+	WindDeg       int           `json:"wind_deg"`
+	WindGust      float64       `json:"wind_gust"`
+	Pop           float64       `json:"pop"`
+	Precipitation float64       `json:"precipitation"`
+	Condition     string        `json:"condition"`
+	Description   string        `json:"description"`
+	Icon          string        `json:"icon"`
+	Correction    *correctionV2 `json:"correction"`
+	Interpolated  bool          `json:"interpolated"`
+}
+
+// correctionV2 - bias corrected temperature of a slot
+type correctionV2 struct {
+	Version int     `json:"version"`
+	Temp    float64 `json:"temp"`
+	Bias    float64 `json:"bias"`
+}
+
+// newSlotV2 converts OpenWeatherMap slot to v2 schema
+func newSlotV2(l models.List) slotV2 {
+	slot := slotV2{
+		Time:          time.Unix(int64(l.Dt), 0).UTC(),
+		Temp:          l.Main.Temp,
+		FeelsLike:     l.Main.FeelsLike,
+		TempMin:       l.Main.TempMin,
+		TempMax:       l.Main.TempMax,
+		Pressure:      l.Main.Pressure,
+		Humidity:      l.Main.Humidity,
+		Clouds:        l.Clouds.All,
+		Visibility:    l.Visibility,
+		WindSpeed:     l.Wind.Speed,
+		WindDeg:       l.Wind.Deg,
+		WindGust:      l.Wind.Gust,
+		Pop:           l.Pop,
+		Precipitation: l.Precipitation3h(),
+		Interpolated:  l.Interpolated,
+	}
+	if len(l.Weather) > 0 {
+		slot.Condition = l.Weather[0].Main
+		slot.Description = l.Weather[0].Description
+		slot.Icon = l.Weather[0].Icon
+	}
+	if l.Correction != nil {
+		slot.Correction = &correctionV2{Version: l.Correction.Version, Temp: l.Correction.Temp, Bias: l.Correction.Bias}
+	}
+	return slot
+}
+
+// cityIDParam returns city id from path of the request
+func cityIDParam(r *http.Request) (int, error) {
+	return strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+}
+
+// GetSummaryV2 returns forecast of concrete city summarized by local day
+func (h Handler) GetSummaryV2(w http.ResponseWriter, r *http.Request) {
+	cityID, err := cityIDParam(r)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	summaries, err := h.repo.GetDailySummaries(r.Context(), cityID)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	summary := summaryV2{
+		CityID:    cityID,
+		UTCOffset: summaries[0].UTCOffset,
+		Days:      make([]daySummaryV2, 0, len(summaries)),
+	}
+	for _, day := range summaries {
+		summary.Days = append(summary.Days, newDaySummaryV2(day))
+	}
+
+	handlers.Render(w, r, summary)
+}
+
+// GetForecastByDateV2 returns forecast for concrete date in v2 schema
+func (h Handler) GetForecastByDateV2(w http.ResponseWriter, r *http.Request) {
+	cityID, err := cityIDParam(r)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	forecasts, err := h.repo.GetForecastByCityIDandDate(r.Context(), cityID, httprouter.ParamsFromContext(r.Context()).ByName("date"))
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+	// city has one forecast per date
+	if len(forecasts) == 0 {
+		handlers.WriteError(w, r, database.ErrForecastNotFound)
+		return
+	}
+
+	forecast := dayForecastV2{
+		CityID: cityID,
+		Date:   forecasts[0].Date.Format("2006-01-02"),
+		Temp:   forecasts[0].Temp,
+		Slots:  make([]slotV2, 0, len(forecasts[0].AdditionalInfo)),
+	}
+	for _, l := range forecasts[0].AdditionalInfo {
+		forecast.Slots = append(forecast.Slots, newSlotV2(l))
+	}
+
+	handlers.Render(w, r, forecast)
+}
+
+// GetForecastByDateTimeV2 returns forecast for concrete date and time in v2 schema, interpolated between 3-hour slots
+func (h Handler) GetForecastByDateTimeV2(w http.ResponseWriter, r *http.Request) {
+	cityID, err := cityIDParam(r)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+	slot, err := interpolation.ForecastAt(r.Context(), h.repo, cityID, params.ByName("date"), params.ByName("time"))
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	handlers.Render(w, r, newSlotV2(*slot))
+}
+
+// GetForecastRangeV2 returns 3-hour forecasts valid in time range in v2 schema, next 24 hours by default
+func (h Handler) GetForecastRangeV2(w http.ResponseWriter, r *http.Request) {
+	cityID, err := cityIDParam(r)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	from, to, err := utils.ParseUpcomingRange(query.Get("from"), query.Get("to"), defaultRangeSpan)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	slots, err := h.repo.GetForecastRange(r.Context(), cityID, from, to)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	forecast := rangeForecastV2{
+		CityID: cityID,
+		From:   from,
+		To:     to,
+		Slots:  make([]slotV2, 0, len(slots)),
+	}
+	for _, l := range slots {
+		forecast.Slots = append(forecast.Slots, newSlotV2(l))
+	}
+
+	handlers.Render(w, r, forecast)
+}
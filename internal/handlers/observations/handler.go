@@ -0,0 +1,144 @@
+package observations
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/handlers"
+	"weather_service/internal/models"
+	"weather_service/pkg/utils"
+)
+
+const (
+	observationsPath   = "/api/cities/:id/observations"
+	observationsV2Path = "/api/v2/cities/:id/observations"
+)
+
+type Handler struct {
+	repo database.Repository
+}
+
+func NewHandler(repo database.Repository) *Handler {
+	return &Handler{
+		repo: repo,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodPost, observationsPath, handlers.Deprecated(observationsV2Path, h.CreateObservation))
+	router.HandlerFunc(http.MethodGet, observationsPath, handlers.Deprecated(observationsV2Path, h.GetObservations))
+
+	// observations already follow v2 schema
+	router.HandlerFunc(http.MethodPost, observationsV2Path, h.CreateObservation)
+	router.HandlerFunc(http.MethodGet, observationsV2Path, h.GetObservations)
+}
+
+// observationRequest is a reading posted by a sensor, temperature is required
+type observationRequest struct {
+	ObservedAt    time.Time `json:"observed_at"`
+	Source        string    `json:"source"`
+	Temp          *float64  `json:"temp"`
+	Humidity      *int      `json:"humidity"`
+	Pressure      *int      `json:"pressure"`
+	WindSpeed     *float64  `json:"wind_speed"`
+	WindDeg       *int      `json:"wind_deg"`
+	Clouds        *int      `json:"clouds"`
+	Precipitation *float64  `json:"precipitation"`
+	Condition     string    `json:"condition"`
+}
+
+// validate checks request and converts it to observation for concrete city
+func (req observationRequest) validate(cityID int) (*models.Observation, error) {
+	if req.Temp == nil {
+		return nil, errors.New("temp is required")
+	}
+	if req.ObservedAt.IsZero() {
+		req.ObservedAt = time.Now()
+	}
+	if req.ObservedAt.After(time.Now().Add(time.Minute)) {
+		return nil, errors.New("observed_at can not be in the future")
+	}
+	if req.Source == "" {
+		req.Source = models.SourceSensor
+	}
+	return &models.Observation{
+		CityID:        cityID,
+		ObservedAt:    req.ObservedAt.UTC(),
+		Source:        req.Source,
+		Temp:          *req.Temp,
+		Humidity:      req.Humidity,
+		Pressure:      req.Pressure,
+		WindSpeed:     req.WindSpeed,
+		WindDeg:       req.WindDeg,
+		Clouds:        req.Clouds,
+		Precipitation: req.Precipitation,
+		Condition:     req.Condition,
+	}, nil
+}
+
+// CreateObservation saves a reading from our own sensor
+func (h *Handler) CreateObservation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	cityID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	var req observationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+	observation, err := req.validate(cityID)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	if err := h.repo.CreateObservation(r.Context(), observation); err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := utils.WriteJSONIndented(w, observation); err != nil {
+		log.Println(err)
+	}
+}
+
+// GetObservations returns observations for concrete city in time range, last 24 hours by default
+func (h *Handler) GetObservations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	cityID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	from, to, err := utils.ParseTimeRange(query.Get("from"), query.Get("to"), 24*time.Hour)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	observations, err := h.repo.GetObservations(r.Context(), cityID, from, to)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	if err := utils.WriteJSONIndented(w, observations); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,133 @@
+package rankings
+
+import (
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/handlers"
+	"weather_service/internal/ranking"
+	"weather_service/pkg/utils"
+)
+
+const (
+	rankingsV2Path = "/api/v2/rankings"
+
+	// defaultRankingSpan - time range when to is not given, the whole forecast horizon
+	defaultRankingSpan = 5 * 24 * time.Hour
+)
+
+type Handler struct {
+	repo database.Repository
+}
+
+func NewHandler(repo database.Repository) *Handler {
+	return &Handler{
+		repo: repo,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, rankingsV2Path, h.GetRankingV2)
+}
+
+// rankingV2 - cities ordered by metric for time range
+type rankingV2 struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Metric  string             `json:"metric"`
+	Order   string             `json:"order"`
+	Weights map[string]float64 `json:"weights,omitempty"`
+	Cities  []ranking.Entry    `json:"cities"`
+}
+
+// GetRankingV2 ranks cities by forecast metric for time range: temp, precipitation, wind, sunshine
+// or weighted score, next 5 days by default. A date in to includes the whole day
+func (h *Handler) GetRankingV2(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	metric := query.Get("metric")
+	var weights map[string]float64
+	if value := query.Get("weights"); value != "" {
+		if metric != "" && metric != ranking.MetricScore {
+			handlers.WriteBadRequest(w, r, errors.New("weights are only used with metric score"))
+			return
+		}
+		var err error
+		if weights, err = ranking.ParseWeights(value); err != nil {
+			handlers.WriteBadRequest(w, r, err)
+			return
+		}
+		metric = ranking.MetricScore
+	}
+	if metric == "" {
+		metric = ranking.MetricTemp
+	}
+	if !ranking.ValidMetric(metric) {
+		handlers.WriteBadRequest(w, r, fmt.Errorf("invalid metric %q: expected temp, precipitation, wind, sunshine or score", metric))
+		return
+	}
+	if metric == ranking.MetricScore && weights == nil {
+		// all metrics count equally by default
+		weights = map[string]float64{
+			ranking.MetricTemp:          1,
+			ranking.MetricPrecipitation: 1,
+			ranking.MetricWind:          1,
+			ranking.MetricSunshine:      1,
+		}
+	}
+
+	descending := ranking.Descending(metric)
+	switch order := query.Get("order"); order {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		handlers.WriteBadRequest(w, r, fmt.Errorf("invalid order %q: expected asc or desc", order))
+		return
+	}
+
+	toValue := query.Get("to")
+	from, to, err := utils.ParseUpcomingRange(query.Get("from"), toValue, defaultRankingSpan)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+	// to=2024-07-14 ranks by Sunday slots too
+	if _, err := time.Parse("2006-01-02", toValue); err == nil {
+		to = to.Add(24*time.Hour - time.Second)
+	}
+
+	cities, err := h.repo.GetAllCities(r.Context())
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+	slots, err := h.repo.GetCitiesForecastRange(r.Context(), nil, from, to)
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	response := rankingV2{
+		From:    from,
+		To:      to,
+		Metric:  metric,
+		Order:   "asc",
+		Weights: weights,
+		Cities:  ranking.Rank(cities, slots, metric, weights, descending),
+	}
+	if descending {
+		response.Order = "desc"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := utils.WriteJSONIndented(w, response); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"weather_service/pkg/render"
+)
+
+// Render writes data in format chosen by format query parameter or Accept header: compact or indented JSON,
+// CSV for data implementing render.Tabular, XML or MessagePack. Unknown format is 400, unsupported Accept is 406
+func Render(w http.ResponseWriter, r *http.Request, data interface{}) {
+	format, err := render.Negotiate(r)
+	if err != nil {
+		writeRenderError(w, r, err)
+		return
+	}
+	body, err := render.Encode(format, data)
+	if err != nil {
+		writeRenderError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", render.ContentType(format))
+	w.Header().Add("Vary", "Accept")
+	if _, err := w.Write(body); err != nil {
+		log.Println(err)
+	}
+}
+
+// writeRenderError writes problem for failed content negotiation or encoding
+func writeRenderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, render.ErrUnknownFormat):
+		WriteBadRequest(w, r, err)
+	case errors.Is(err, render.ErrNotAcceptable):
+		WriteProblem(w, r, http.StatusNotAcceptable, CodeNotAcceptable, err.Error())
+	default:
+		log.Println("Render error:", err)
+		WriteProblem(w, r, http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+	}
+}
@@ -0,0 +1,101 @@
+package verification
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/handlers"
+	"weather_service/internal/verification"
+	"weather_service/pkg/utils"
+)
+
+const (
+	cityVerificationPath   = "/api/cities/:id/verification"
+	verificationPath       = "/api/verification"
+	cityVerificationV2Path = "/api/v2/cities/:id/verification"
+	verificationV2Path     = "/api/v2/verification"
+)
+
+type Handler struct {
+	repo database.Repository
+}
+
+func NewHandler(repo database.Repository) *Handler {
+	return &Handler{
+		repo: repo,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, cityVerificationPath, handlers.Deprecated(cityVerificationV2Path, h.GetCityVerification))
+	router.HandlerFunc(http.MethodGet, verificationPath, handlers.Deprecated(verificationV2Path, h.GetVerification))
+
+	// reports already follow v2 schema
+	router.HandlerFunc(http.MethodGet, cityVerificationV2Path, h.GetCityVerification)
+	router.HandlerFunc(http.MethodGet, verificationV2Path, h.GetVerification)
+}
+
+// GetCityVerification returns forecast quality report for concrete city
+func (h *Handler) GetCityVerification(w http.ResponseWriter, r *http.Request) {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	cityID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	h.writeReport(w, r, cityID)
+}
+
+// GetVerification returns forecast quality report aggregated across all cities
+func (h *Handler) GetVerification(w http.ResponseWriter, r *http.Request) {
+	h.writeReport(w, r, 0)
+}
+
+// writeReport computes report for query parameters lead, from, to and provider, last 7 days by default
+func (h *Handler) writeReport(w http.ResponseWriter, r *http.Request, cityID int) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	var lead time.Duration
+	if value := query.Get("lead"); value != "" {
+		var err error
+		lead, err = time.ParseDuration(value)
+		if err != nil || lead < 0 {
+			handlers.WriteBadRequest(w, r, errors.New("invalid lead: expected duration like 24h"))
+			return
+		}
+	}
+
+	from, to, err := utils.ParseTimeRange(query.Get("from"), query.Get("to"), 7*24*time.Hour)
+	if err != nil {
+		handlers.WriteBadRequest(w, r, err)
+		return
+	}
+
+	pairs, err := h.repo.GetVerificationPairs(r.Context(), database.VerificationFilter{
+		CityID:   cityID,
+		Provider: query.Get("provider"),
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		handlers.WriteError(w, r, err)
+		return
+	}
+
+	report := verification.Report{
+		CityID: cityID,
+		From:   from,
+		To:     to,
+		Scores: verification.Compute(pairs, lead),
+	}
+	if err := utils.WriteJSONIndented(w, report); err != nil {
+		log.Println(err)
+	}
+}
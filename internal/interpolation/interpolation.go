@@ -0,0 +1,153 @@
+package interpolation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/models"
+)
+
+// ForecastAt returns forecast of a city for date and clock time (UTC). A slot valid exactly at that time is returned
+// as stored, otherwise numeric values are interpolated between the surrounding slots, which may belong
+// to the previous or the next day. ErrForecastNotFound is returned when the time is outside of the forecast
+func ForecastAt(ctx context.Context, repo database.Repository, cityID int, date, clock string) (*models.List, error) {
+	day, err := database.ParseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := database.ParseClock(clock)
+	if err != nil {
+		return nil, err
+	}
+	at := day.Add(offset)
+
+	slots, err := daySlots(ctx, repo, cityID, day)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return nil, database.ErrForecastNotFound
+	}
+
+	// the surrounding slot of the earliest and the latest time of the day is in the neighbouring day
+	if at.Before(slots[0].DtTime) {
+		previous, err := daySlots(ctx, repo, cityID, day.AddDate(0, 0, -1))
+		if err != nil && !errors.Is(err, database.ErrForecastNotFound) {
+			return nil, err
+		}
+		slots = append(previous, slots...)
+	}
+	if at.After(slots[len(slots)-1].DtTime) {
+		next, err := daySlots(ctx, repo, cityID, day.AddDate(0, 0, 1))
+		if err != nil && !errors.Is(err, database.ErrForecastNotFound) {
+			return nil, err
+		}
+		slots = append(slots, next...)
+	}
+
+	slot, ok := At(slots, at)
+	if !ok {
+		return nil, database.ErrForecastNotFound
+	}
+	return &slot, nil
+}
+
+// daySlots returns slots of the forecast for day ordered by time
+func daySlots(ctx context.Context, repo database.Repository, cityID int, day time.Time) ([]models.List, error) {
+	forecasts, err := repo.GetForecastByCityIDandDate(ctx, cityID, day.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	slots := make([]models.List, 0)
+	for _, forecast := range forecasts {
+		slots = append(slots, forecast.AdditionalInfo...)
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].DtTime.Before(slots[j].DtTime)
+	})
+	return slots, nil
+}
+
+// At returns slot valid at time at from slots ordered by time, interpolating between the surrounding slots.
+// It reports false when at is before the first or after the last slot
+func At(slots []models.List, at time.Time) (models.List, bool) {
+	i := sort.Search(len(slots), func(i int) bool {
+		return !slots[i].DtTime.Before(at)
+	})
+	if i == len(slots) || i == 0 && !slots[0].DtTime.Equal(at) {
+		return models.List{}, false
+	}
+	if slots[i].DtTime.Equal(at) {
+		return slots[i], true
+	}
+	return Interpolate(slots[i-1], slots[i], at), true
+}
+
+// Interpolate returns slot at time at between before and after. Temperature, humidity, pressure, wind, clouds,
+// visibility and probability of precipitation change linearly, wind direction along the shorter arc.
+// Weather condition, precipitation volume and day part are taken from the nearest slot. Bias correction is interpolated
+// when both slots are corrected by the same version and left out otherwise
+func Interpolate(before, after models.List, at time.Time) models.List {
+	f := float64(at.Sub(before.DtTime)) / float64(after.DtTime.Sub(before.DtTime))
+
+	slot := after
+	if f < 0.5 {
+		slot = before
+	}
+	slot.Weather = append([]models.Weather(nil), slot.Weather...)
+	slot.Main = models.Main{
+		Temp:      lerp(before.Main.Temp, after.Main.Temp, f),
+		FeelsLike: lerp(before.Main.FeelsLike, after.Main.FeelsLike, f),
+		TempMin:   lerp(before.Main.TempMin, after.Main.TempMin, f),
+		TempMax:   lerp(before.Main.TempMax, after.Main.TempMax, f),
+		Pressure:  lerpInt(before.Main.Pressure, after.Main.Pressure, f),
+		SeaLevel:  lerpInt(before.Main.SeaLevel, after.Main.SeaLevel, f),
+		GrndLevel: lerpInt(before.Main.GrndLevel, after.Main.GrndLevel, f),
+		Humidity:  lerpInt(before.Main.Humidity, after.Main.Humidity, f),
+		TempKf:    lerp(before.Main.TempKf, after.Main.TempKf, f),
+	}
+	slot.Wind = models.Wind{
+		Speed: lerp(before.Wind.Speed, after.Wind.Speed, f),
+		Deg:   lerpDegrees(before.Wind.Deg, after.Wind.Deg, f),
+		Gust:  lerp(before.Wind.Gust, after.Wind.Gust, f),
+	}
+	slot.Clouds = models.Clouds{All: lerpInt(before.Clouds.All, after.Clouds.All, f)}
+	slot.Visibility = lerpInt(before.Visibility, after.Visibility, f)
+	slot.Pop = lerp(before.Pop, after.Pop, f)
+	if before.Correction != nil && after.Correction != nil && before.Correction.Version == after.Correction.Version {
+		slot.Correction = &models.Correction{
+			Version: before.Correction.Version,
+			Temp:    lerp(before.Correction.Temp, after.Correction.Temp, f),
+			Bias:    lerp(before.Correction.Bias, after.Correction.Bias, f),
+		}
+	} else {
+		// correction of the nearest slot belongs to another hour
+		slot.Correction = nil
+	}
+
+	slot.Dt = int(at.Unix())
+	slot.DtTime = at.UTC()
+	slot.DtTxt = slot.DtTime.Format("2006-01-02 15:04:05")
+	slot.Interpolated = true
+	return slot
+}
+
+// lerp returns value between a and b at fraction f
+func lerp(a, b, f float64) float64 {
+	return a + (b-a)*f
+}
+
+// lerpInt returns rounded value between a and b at fraction f
+func lerpInt(a, b int, f float64) int {
+	return int(math.Round(lerp(float64(a), float64(b), f)))
+}
+
+// lerpDegrees returns direction between a and b at fraction f turning along the shorter arc
+func lerpDegrees(a, b int, f float64) int {
+	diff := math.Mod(float64(b-a)+540, 360) - 180
+	deg := math.Mod(float64(a)+diff*f+360, 360)
+	return int(math.Round(deg)) % 360
+}
@@ -0,0 +1,124 @@
+package interpolation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/database/memory"
+	"weather_service/internal/models"
+)
+
+// slot returns forecast slot valid at at
+func slot(at time.Time, temp float64, humidity, deg int, condition string) models.List {
+	return models.List{
+		Dt:      int(at.Unix()),
+		Main:    models.Main{Temp: temp, Humidity: humidity, Pressure: 1010},
+		Wind:    models.Wind{Speed: 2, Deg: deg},
+		Weather: []models.Weather{{Main: condition}},
+		Pop:     0.3,
+		DtTxt:   at.Format("2006-01-02 15:04:05"),
+		DtTime:  at,
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	noon := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	before := slot(noon, 20, 60, 350, "Clear")
+	after := slot(noon.Add(3*time.Hour), 26, 40, 20, "Rain")
+	after.Main.Pressure = 1016
+
+	got := Interpolate(before, after, noon.Add(time.Hour))
+	if got.Main.Temp != 22 || got.Main.Humidity != 53 || got.Main.Pressure != 1012 {
+		t.Errorf("Expected values a third of the way, got %+v", got.Main)
+	}
+	// 350° to 20° turns through north
+	if got.Wind.Deg != 0 {
+		t.Errorf("Expected wind direction 0, got %d", got.Wind.Deg)
+	}
+	if got.Weather[0].Main != "Clear" || !got.Interpolated {
+		t.Errorf("Expected condition of the nearest slot and interpolated flag, got %+v", got)
+	}
+	if !got.DtTime.Equal(noon.Add(time.Hour)) || got.DtTxt != "2024-07-11 13:00:00" || got.Dt != int(noon.Add(time.Hour).Unix()) {
+		t.Errorf("Expected time of the request, got %v %s %d", got.DtTime, got.DtTxt, got.Dt)
+	}
+
+	if got := Interpolate(before, after, noon.Add(2*time.Hour)); got.Weather[0].Main != "Rain" {
+		t.Errorf("Expected condition of the later slot, got %s", got.Weather[0].Main)
+	}
+}
+
+func TestInterpolateCorrection(t *testing.T) {
+	noon := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	before := slot(noon, 20, 60, 0, "Clear")
+	after := slot(noon.Add(3*time.Hour), 26, 40, 0, "Clear")
+	before.Correction = &models.Correction{Version: 2, Temp: 19, Bias: 1}
+	after.Correction = &models.Correction{Version: 2, Temp: 24, Bias: 2}
+
+	if got := Interpolate(before, after, noon.Add(time.Hour)).Correction; got == nil || got.Version != 2 || math.Abs(got.Temp-62.0/3) > 1e-9 || math.Abs(got.Bias-4.0/3) > 1e-9 {
+		t.Errorf("Expected correction a third of the way, got %+v", got)
+	}
+
+	// only the nearest slot is corrected, its values belong to noon
+	after.Correction = nil
+	if got := Interpolate(before, after, noon.Add(time.Hour)); got.Correction != nil {
+		t.Errorf("Expected no correction with one corrected slot, got %+v", got.Correction)
+	}
+	after.Correction = &models.Correction{Version: 3, Temp: 24, Bias: 2}
+	if got := Interpolate(before, after, noon.Add(2*time.Hour)); got.Correction != nil {
+		t.Errorf("Expected no correction with different versions, got %+v", got.Correction)
+	}
+}
+
+func TestForecastAt(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	city := &models.City{Name: "Berlin", Country: "DE"}
+	if err := repo.CreateCity(ctx, city); err != nil {
+		t.Fatalf("CreateCity: %v", err)
+	}
+
+	day := time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC)
+	for _, forecast := range []models.WeatherInfo{
+		{Date: day, AdditionalInfo: []models.List{slot(day.Add(18*time.Hour), 20, 50, 90, "Clear"), slot(day.Add(21*time.Hour), 17, 60, 90, "Clear")}},
+		{Date: day.AddDate(0, 0, 1), AdditionalInfo: []models.List{slot(day.Add(24*time.Hour), 14, 70, 90, "Clouds")}},
+	} {
+		if err := repo.CreateForecast(ctx, &forecast, city.ID); err != nil {
+			t.Fatalf("CreateForecast: %v", err)
+		}
+	}
+
+	tests := []struct {
+		date, clock  string
+		temp         float64
+		interpolated bool
+		err          error
+	}{
+		{"2024-07-11", "21:00:00", 17, false, nil},
+		{"2024-07-11", "19:30", 18.5, true, nil},
+		// the next slot is in the next day
+		{"2024-07-11", "22:30:00", 15.5, true, nil},
+		// the previous slot is in the previous day
+		{"2024-07-12", "00:00:00", 14, false, nil},
+		{"2024-07-12", "03:00:00", 0, false, database.ErrForecastNotFound},
+		{"2024-07-11", "09:00:00", 0, false, database.ErrForecastNotFound},
+		{"2024-07-11", "25:00", 0, false, database.ErrInvalidDate},
+	}
+	for _, tt := range tests {
+		got, err := ForecastAt(ctx, repo, city.ID, tt.date, tt.clock)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s %s: expected %v, got %v", tt.date, tt.clock, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.date, tt.clock, err)
+		}
+		if got.Main.Temp != tt.temp || got.Interpolated != tt.interpolated {
+			t.Errorf("%s %s: expected temp %v interpolated %v, got %v %v", tt.date, tt.clock, tt.temp, tt.interpolated, got.Main.Temp, got.Interpolated)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// BiasCorrection represents one immutable trained version of temperature corrections,
+// training window and minimal number of samples are kept to reproduce it
+type BiasCorrection struct {
+	Version    int          `json:"version"`
+	TrainedAt  time.Time    `json:"trained_at"`
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	MinSamples int          `json:"min_samples"`
+	Offsets    []BiasOffset `json:"offsets"`
+}
+
+// BiasOffset represents mean forecast error for a city at an hour of day (UTC) and a lead time
+type BiasOffset struct {
+	CityID    int     `json:"city_id"`
+	Hour      int     `json:"hour"`
+	LeadHours int     `json:"lead_hours"`
+	Bias      float64 `json:"bias"`
+	Samples   int     `json:"samples"`
+}
+
+// Correction represents corrected temperature of a forecast slot, raw value stays in Main.Temp
+type Correction struct {
+	Version int     `json:"version"`
+	Temp    float64 `json:"temp"`
+	Bias    float64 `json:"bias"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// CurrentWeather struct for OpenWeatherMap API current weather response
+type CurrentWeather struct {
+	Dt      int64         `json:"dt"`
+	Main    Main          `json:"main"`
+	Weather []Weather     `json:"weather"`
+	Clouds  Clouds        `json:"clouds"`
+	Wind    Wind          `json:"wind"`
+	Rain    Precipitation `json:"rain"`
+	Snow    Precipitation `json:"snow"`
+}
+
+// Precipitation struct for rain and snow volume in mm
+type Precipitation struct {
+	OneHour   float64 `json:"1h,omitempty"`
+	ThreeHour float64 `json:"3h,omitempty"`
+}
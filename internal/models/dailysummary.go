@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// DailySummary - aggregated forecast of a city for one local calendar day
+type DailySummary struct {
+	CityID int       `json:"city_id"`
+	Date   time.Time `json:"date"`
+	// UTCOffset - offset of city local time from UTC in seconds
+	UTCOffset     int     `json:"utc_offset"`
+	TempMin       float64 `json:"temp_min"`
+	TempMax       float64 `json:"temp_max"`
+	TempMean      float64 `json:"temp_mean"`
+	TempMidday    float64 `json:"temp_midday"`
+	Precipitation float64 `json:"precipitation"`
+	WindMax       float64 `json:"wind_max"`
+	GustMax       float64 `json:"gust_max"`
+	PopMax        float64 `json:"pop_max"`
+	Condition     string  `json:"condition"`
+	// Icon - OpenWeatherMap icon of the dominant condition nearest to local noon
+	Icon string `json:"icon"`
+	// Partial - the day is not covered by all 8 slots of the forecast run, e.g. today after the first hours passed
+	Partial bool `json:"partial"`
+}
@@ -4,23 +4,37 @@ import "time"
 
 // Forecast struct for OpenWeatherMap API response with list of forecasts for 5 days
 type Forecast struct {
-	Cod     string `json:"cod"`
-	Message int    `json:"message"`
-	Cnt     int    `json:"cnt"`
-	List    []List `json:"list"`
+	Cod     string       `json:"cod"`
+	Message int          `json:"message"`
+	Cnt     int          `json:"cnt"`
+	List    []List       `json:"list"`
+	City    ForecastCity `json:"city"`
+}
+
+// ForecastCity - city of OpenWeatherMap forecast, timezone is offset from UTC in seconds
+type ForecastCity struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Country  string `json:"country"`
+	Timezone int    `json:"timezone"`
 }
 
 type List struct {
-	Dt         int       `json:"dt"`
-	Main       Main      `json:"main"`
-	Weather    []Weather `json:"weather"`
-	Clouds     Clouds    `json:"clouds"`
-	Wind       Wind      `json:"wind"`
-	Visibility int       `json:"visibility"`
-	Pop        float64   `json:"pop"`
-	Sys        Sys       `json:"sys"`
-	DtTxt      string    `json:"dt_txt"`
-	DtTime     time.Time `json:"dt_time"`
+	Dt         int            `json:"dt"`
+	Main       Main           `json:"main"`
+	Weather    []Weather      `json:"weather"`
+	Clouds     Clouds         `json:"clouds"`
+	Wind       Wind           `json:"wind"`
+	Visibility int            `json:"visibility"`
+	Pop        float64        `json:"pop"`
+	Rain       *Precipitation `json:"rain,omitempty"`
+	Snow       *Precipitation `json:"snow,omitempty"`
+	Sys        Sys            `json:"sys"`
+	Correction *Correction    `json:"correction,omitempty"`
+	DtTxt      string         `json:"dt_txt"`
+	DtTime     time.Time      `json:"dt_time"`
+	// Interpolated is set when numeric values are interpolated between two slots for a time without its own slot
+	Interpolated bool `json:"interpolated,omitempty"`
 }
 
 type Main struct {
@@ -55,3 +69,15 @@ type Wind struct {
 type Sys struct {
 	Pod string `json:"pod"`
 }
+
+// Precipitation3h returns rain and snow volume for 3 hours in mm
+func (l List) Precipitation3h() float64 {
+	var volume float64
+	if l.Rain != nil {
+		volume += l.Rain.ThreeHour
+	}
+	if l.Snow != nil {
+		volume += l.Snow.ThreeHour
+	}
+	return volume
+}
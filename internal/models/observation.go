@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	// SourceOpenWeatherMap marks data received from OpenWeatherMap API
+	SourceOpenWeatherMap = "openweathermap"
+	// SourceSensor marks observations posted by our own sensors
+	SourceSensor = "sensor"
+)
+
+// Observation represents actually measured weather for a city at a moment of time,
+// optional values are nil when the source does not measure them
+type Observation struct {
+	ID            int       `json:"id"`
+	CityID        int       `json:"city_id"`
+	ObservedAt    time.Time `json:"observed_at"`
+	Source        string    `json:"source"`
+	Temp          float64   `json:"temp"`
+	Humidity      *int      `json:"humidity,omitempty"`
+	Pressure      *int      `json:"pressure,omitempty"`
+	WindSpeed     *float64  `json:"wind_speed,omitempty"`
+	WindDeg       *int      `json:"wind_deg,omitempty"`
+	Clouds        *int      `json:"clouds,omitempty"`
+	Precipitation *float64  `json:"precipitation,omitempty"`
+	Condition     string    `json:"condition,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ForecastSlot represents one 3-hour forecast value as it was issued by a provider,
+// kept to compare it later with the observed weather
+type ForecastSlot struct {
+	CityID        int       `json:"city_id"`
+	Provider      string    `json:"provider"`
+	IssuedAt      time.Time `json:"issued_at"`
+	ValidAt       time.Time `json:"valid_at"`
+	Temp          float64   `json:"temp"`
+	Pop           float64   `json:"pop"`
+	Precipitation float64   `json:"precipitation"`
+}
+
+// VerificationPair represents forecast slot matched with the nearest observation
+type VerificationPair struct {
+	CityID                int
+	Provider              string
+	IssuedAt              time.Time
+	ValidAt               time.Time
+	ForecastTemp          float64
+	ForecastPop           float64
+	ObservedAt            time.Time
+	ObservedTemp          float64
+	ObservedPrecipitation *float64
+	ObservedCondition     string
+}
+
+// Lead returns how long before valid time the forecast was issued
+func (p VerificationPair) Lead() time.Duration {
+	return p.ValidAt.Sub(p.IssuedAt)
+}
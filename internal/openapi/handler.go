@@ -0,0 +1,61 @@
+package openapi
+
+import (
+	_ "embed"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"weather_service/internal/handlers"
+)
+
+const (
+	documentPath = "/api/openapi.json"
+	docsPath     = "/api/docs"
+)
+
+// docsPage renders the document with Redoc
+//
+//go:embed docs.html
+var docsPage []byte
+
+type Handler struct {
+	spec *Spec
+}
+
+func NewHandler(spec *Spec) *Handler {
+	return &Handler{
+		spec: spec,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, documentPath, h.GetDocument)
+	router.HandlerFunc(http.MethodGet, docsPath, h.GetDocs)
+}
+
+// GetDocument returns OpenAPI document
+func (h *Handler) GetDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(Document()); err != nil {
+		log.Println(err)
+	}
+}
+
+// GetDocs returns documentation page
+func (h *Handler) GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(docsPage); err != nil {
+		log.Println(err)
+	}
+}
+
+// Validate rejects requests with path or query parameters that do not match the document before they reach handlers
+func (h *Handler) Validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.spec.ValidateRequest(r); err != nil {
+			handlers.WriteBadRequest(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
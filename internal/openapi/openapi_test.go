@@ -0,0 +1,242 @@
+package openapi_test
+
+import (
+	"context"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/database/memory"
+	"weather_service/internal/handlers"
+	"weather_service/internal/handlers/cities"
+	"weather_service/internal/handlers/forecasts"
+	"weather_service/internal/handlers/observations"
+	"weather_service/internal/handlers/rankings"
+	"weather_service/internal/handlers/verification"
+	"weather_service/internal/models"
+	"weather_service/internal/openapi"
+)
+
+// newServer returns all handlers of the service behind validation like in main
+func newServer(t *testing.T, spec *openapi.Spec, repo database.Repository) http.Handler {
+	t.Helper()
+	router := httprouter.New()
+	router.NotFound = http.HandlerFunc(handlers.NotFound)
+	router.MethodNotAllowed = http.HandlerFunc(handlers.MethodNotAllowed)
+	for _, handler := range []handlers.Handler{
+		cities.NewHandler(repo),
+		forecasts.NewHandler(repo),
+		observations.NewHandler(repo),
+		verification.NewHandler(repo),
+		rankings.NewHandler(repo),
+	} {
+		handler.Register(router)
+	}
+	openapiHandler := openapi.NewHandler(spec)
+	openapiHandler.Register(router)
+	return handlers.RequestID(openapiHandler.Validate(router))
+}
+
+// seed saves a city with forecast of today, daily summary, observation and issued slot
+func seed(t *testing.T, repo database.Repository) (cityID int, today time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	today = now.Truncate(24 * time.Hour)
+
+	city := &models.City{Name: "Berlin", Country: "DE", Latitude: 52.52, Longitude: 13.4}
+	if err := repo.CreateCity(ctx, city); err != nil {
+		t.Fatalf("CreateCity: %v", err)
+	}
+
+	forecast := models.WeatherInfo{CityID: city.ID, Date: today, Temp: 21}
+	for _, hour := range []int{9, 12, 15} {
+		dt := today.Add(time.Duration(hour) * time.Hour)
+		forecast.AdditionalInfo = append(forecast.AdditionalInfo, models.List{
+			Dt:      int(dt.Unix()),
+			Main:    models.Main{Temp: 21, Humidity: 60, Pressure: 1012},
+			Weather: []models.Weather{{ID: 500, Main: "Rain", Description: "light rain", Icon: "10d"}},
+			Clouds:  models.Clouds{All: 75},
+			Wind:    models.Wind{Speed: 3.5, Deg: 270, Gust: 6},
+			Pop:     0.6,
+			Rain:    &models.Precipitation{ThreeHour: 1.2},
+			Sys:     models.Sys{Pod: "d"},
+			Correction: &models.Correction{
+				Version: 1,
+				Temp:    20.5,
+				Bias:    0.5,
+			},
+			DtTxt:  dt.Format("2006-01-02 15:04:05"),
+			DtTime: dt,
+		})
+	}
+	summaries := []models.DailySummary{{CityID: city.ID, Date: today, TempMin: 19, TempMax: 23, TempMean: 21, TempMidday: 21, Condition: "Rain"}}
+	slots := []models.ForecastSlot{{
+		CityID:   city.ID,
+		Provider: models.SourceOpenWeatherMap,
+		IssuedAt: now.Add(-5 * time.Hour).Truncate(time.Hour),
+		ValidAt:  now.Add(-2 * time.Hour).Truncate(time.Hour),
+		Temp:     20,
+		Pop:      0.6,
+	}}
+	if err := repo.ReplaceCityForecasts(ctx, city.ID, []models.WeatherInfo{forecast}, slots, summaries); err != nil {
+		t.Fatalf("ReplaceCityForecasts: %v", err)
+	}
+
+	precipitation := 0.4
+	observation := &models.Observation{
+		CityID:        city.ID,
+		ObservedAt:    now.Add(-2 * time.Hour).Truncate(time.Hour),
+		Source:        models.SourceSensor,
+		Temp:          19,
+		Precipitation: &precipitation,
+		Condition:     "Rain",
+	}
+	if err := repo.CreateObservation(ctx, observation); err != nil {
+		t.Fatalf("CreateObservation: %v", err)
+	}
+	return city.ID, today
+}
+
+// TestResponsesMatchSpec fails when output of a handler drifts from the document
+// or when a described operation is not exercised here
+func TestResponsesMatchSpec(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	repo := memory.NewMemoryRepository()
+	cityID, today := seed(t, repo)
+	server := newServer(t, spec, repo)
+
+	city := "/api/cities/" + strconv.Itoa(cityID)
+	v2city := "/api/v2/cities/" + strconv.Itoa(cityID)
+	date := today.Format("2006-01-02")
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/api/cities?limit=10&sort=lat", "", http.StatusOK},
+		{http.MethodGet, "/api/cities?format=xml", "", http.StatusOK},
+		{http.MethodGet, city + "/forecasts/shortforecast/", "", http.StatusOK},
+		{http.MethodGet, city + "/forecasts/shortforecast/?format=csv", "", http.StatusOK},
+		{http.MethodGet, "/api/cities/4242/forecasts/shortforecast/", "", http.StatusNotFound},
+		{http.MethodGet, "/api/cities/abc/forecasts/shortforecast/", "", http.StatusBadRequest},
+		{http.MethodGet, city + "/forecasts/fullforecast/" + date + "/", "", http.StatusOK},
+		{http.MethodGet, city + "/forecasts/fullforecast/2024-13-45/", "", http.StatusUnprocessableEntity},
+		{http.MethodGet, city + "/forecasts/fullforecast/tomorrow/", "", http.StatusBadRequest},
+		{http.MethodGet, city + "/forecasts/fullforecast/" + date + "/12:00:00/", "", http.StatusOK},
+		{http.MethodGet, city + "/forecasts/fullforecast/" + date + "/13:30:00/", "", http.StatusOK},
+		{http.MethodGet, city + "/forecasts/fullforecast/" + date + "/noon/", "", http.StatusBadRequest},
+		{http.MethodGet, city + "/forecasts?from=" + date + "T10:00:00Z&to=" + date + "T17:00:00%2B02:00", "", http.StatusOK},
+		{http.MethodGet, city + "/forecasts?from=tomorrow", "", http.StatusBadRequest},
+		{http.MethodGet, city + "/forecasts?from=2000-01-01&to=2100-01-01", "", http.StatusBadRequest},
+		{http.MethodGet, city + "/observations", "", http.StatusOK},
+		{http.MethodGet, city + "/observations?from=yesterday", "", http.StatusBadRequest},
+		{http.MethodPost, city + "/observations", `{"temp": 18.5, "humidity": 70}`, http.StatusCreated},
+		{http.MethodPost, city + "/observations", `{"humidity": 70}`, http.StatusBadRequest},
+		{http.MethodGet, city + "/verification?lead=3h", "", http.StatusOK},
+		{http.MethodGet, "/api/verification", "", http.StatusOK},
+		{http.MethodGet, "/api/verification?lead=tomorrow", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v2/cities", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/cities?limit=1&sort=country&country=de", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/cities?sort=population", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v2/cities?cursor=abc", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v2/cities?format=json", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/cities?format=yaml", "", http.StatusBadRequest},
+		{http.MethodGet, v2city + "/summary", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/cities/4242/summary", "", http.StatusNotFound},
+		{http.MethodGet, v2city + "/forecasts/" + date, "", http.StatusOK},
+		{http.MethodGet, v2city + "/forecasts/" + date + "?format=csv", "", http.StatusOK},
+		{http.MethodGet, v2city + "/forecasts/" + date + "/12:00:00", "", http.StatusOK},
+		{http.MethodGet, v2city + "/forecasts/" + date + "/13:30", "", http.StatusOK},
+		{http.MethodGet, v2city + "/forecasts/" + date + "/23:00:00", "", http.StatusNotFound},
+		{http.MethodGet, v2city + "/forecasts?from=" + date + "&to=%2B48h", "", http.StatusOK},
+		{http.MethodGet, v2city + "/forecasts?from=now&to=-1h", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v2/cities/4242/forecasts", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v2/forecasts", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/forecasts?date=" + date + "&format=msgpack", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/forecasts?city_ids=" + strconv.Itoa(cityID) + "&date=" + date, "", http.StatusOK},
+		{http.MethodGet, "/api/v2/forecasts?city_ids=all&date=" + date + "&time=13:30", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/forecasts?time=13:30", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v2/forecasts?city_ids=" + strconv.Itoa(cityID) + ",4242", "", http.StatusNotFound},
+		{http.MethodGet, v2city + "/observations", "", http.StatusOK},
+		{http.MethodPost, v2city + "/observations", `{"temp": 18.5}`, http.StatusCreated},
+		{http.MethodGet, v2city + "/verification", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/verification?provider=openweathermap", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/rankings?from=" + date + "&to=" + date, "", http.StatusOK},
+		{http.MethodGet, "/api/v2/rankings?weights=temp:2,precipitation:1&order=asc", "", http.StatusOK},
+		{http.MethodGet, "/api/v2/rankings?metric=wind&weights=temp:1", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v2/rankings?metric=humidity", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/api/docs", "", http.StatusOK},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tt.method, tt.url, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		path, operation, _ := spec.Find(tt.method, r.URL.Path)
+		if operation == nil {
+			t.Errorf("%s %s is not described", tt.method, tt.url)
+			continue
+		}
+		if err := spec.ValidateResponse(tt.method, path, w.Code, w.Header().Get("Content-Type"), w.Body.Bytes()); err != nil {
+			t.Error(err)
+		}
+		if w.Code >= 300 {
+			continue
+		}
+		covered[tt.method+" "+path] = true
+
+		// v1 routes link their v2 successors
+		deprecated := w.Header().Get("Deprecation") != "" && strings.Contains(w.Header().Get("Link"), "</api/v2/")
+		if deprecated != operation.Deprecated {
+			t.Errorf("%s %s: expected deprecated %v, got headers %v", tt.method, tt.url, operation.Deprecated, w.Header())
+		}
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if key := strings.ToUpper(method) + " " + path; !covered[key] {
+				t.Errorf("%s has no successful request in the test", key)
+			}
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"/api/cities/1/verification?lead=24h&from=2024-07-01", true},
+		{"/api/cities/0/verification", false},
+		{"/api/cities/1/verification?lead=day", false},
+		{"/api/cities/1/verification?to=2024-07-01T12:00:00Z", true},
+		{"/api/unknown/route", true},
+	}
+	for _, tt := range tests {
+		err := spec.ValidateRequest(httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid %v, got %v", tt.url, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// document - OpenAPI 3 description of every route of the service, keep it in sync with handlers
+//
+//go:embed openapi.json
+var document []byte
+
+// Spec - parsed OpenAPI document, only the parts needed to validate requests and responses are kept
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	routes []route
+}
+
+// Components - reusable parts of the document referenced with $ref
+type Components struct {
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+	Schemas    map[string]*Schema    `json:"schemas"`
+}
+
+// Operation - one method of a path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Deprecated  bool                 `json:"deprecated"`
+	Parameters  []*Parameter         `json:"parameters"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter - path or query parameter of an operation
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Response - response of an operation by media type
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType - schema of a body with concrete media type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema - subset of OpenAPI schema object, additionalProperties only supports boolean values
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	AllOf                []*Schema          `json:"allOf"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Pattern              string             `json:"pattern"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Nullable             bool               `json:"nullable"`
+	Items                *Schema            `json:"items"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+}
+
+// route - path template split into segments, {name} segments match any value
+type route struct {
+	path     string
+	segments []string
+}
+
+// Document returns raw OpenAPI document served to clients
+func Document() []byte {
+	return document
+}
+
+// Load parses embedded document and resolves references of parameters and responses
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(document, &spec); err != nil {
+		return nil, fmt.Errorf("can not parse openapi.json: %w", err)
+	}
+
+	for path, operations := range spec.Paths {
+		for method, operation := range operations {
+			for i, parameter := range operation.Parameters {
+				if parameter.Ref == "" {
+					continue
+				}
+				resolved, ok := spec.Components.Parameters[refName(parameter.Ref)]
+				if !ok {
+					return nil, fmt.Errorf("%s %s: unknown parameter %s", method, path, parameter.Ref)
+				}
+				operation.Parameters[i] = resolved
+			}
+			for status, response := range operation.Responses {
+				if response.Ref == "" {
+					continue
+				}
+				resolved, ok := spec.Components.Responses[refName(response.Ref)]
+				if !ok {
+					return nil, fmt.Errorf("%s %s: unknown response %s", method, path, response.Ref)
+				}
+				operation.Responses[status] = resolved
+			}
+		}
+		spec.routes = append(spec.routes, route{path: path, segments: strings.Split(path, "/")})
+	}
+	// static segments win over parameters like in the router
+	sort.Slice(spec.routes, func(i, j int) bool {
+		return spec.routes[i].path < spec.routes[j].path
+	})
+	return &spec, nil
+}
+
+// Find returns path template and operation matching request method and path with values of path parameters,
+// operation is nil when the document does not describe the request
+func (s *Spec) Find(method, path string) (string, *Operation, map[string]string) {
+	segments := strings.Split(path, "/")
+	for _, route := range s.routes {
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		if operation, ok := s.Paths[route.path][strings.ToLower(method)]; ok {
+			return route.path, operation, params
+		}
+	}
+	return "", nil, nil
+}
+
+// match returns values of path parameters if segments fit the template
+func (r route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// ValidateRequest checks path and query parameters of request described by the document,
+// requests of unknown routes are left to the router
+func (s *Spec) ValidateRequest(r *http.Request) error {
+	_, operation, pathParams := s.Find(r.Method, r.URL.Path)
+	if operation == nil {
+		return nil
+	}
+
+	query := r.URL.Query()
+	for _, parameter := range operation.Parameters {
+		var value string
+		var present bool
+		switch parameter.In {
+		case "path":
+			value, present = pathParams[parameter.Name]
+		case "query":
+			present = query.Has(parameter.Name)
+			value = query.Get(parameter.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if parameter.Required {
+				return fmt.Errorf("%s parameter %q is required", parameter.In, parameter.Name)
+			}
+			continue
+		}
+		if err := s.validateParameter(parameter.Schema, value); err != nil {
+			return fmt.Errorf("invalid %s parameter %q: %w", parameter.In, parameter.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateParameter checks raw parameter value against schema of a scalar type
+func (s *Spec) validateParameter(schema *Schema, value string) error {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected integer, got %q", value)
+		}
+		return checkRange(schema, float64(n))
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected number, got %q", value)
+		}
+		return checkRange(schema, n)
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("expected boolean, got %q", value)
+		}
+		return nil
+	}
+	return checkString(schema, value)
+}
+
+// ValidateResponse checks response of operation at path template against the document,
+// JSON bodies are validated against schema of the status and media type
+func (s *Spec) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	operation, ok := s.Paths[path][strings.ToLower(method)]
+	if !ok {
+		return fmt.Errorf("%s %s is not described", method, path)
+	}
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not described", method, path, status)
+	}
+
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s: %d response with %q is not described", method, path, status, mediaType)
+	}
+	if !strings.HasSuffix(mediaType, "json") {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON body: %w", method, path, err)
+	}
+	if err := s.validateValue(content.Schema, value, "body"); err != nil {
+		return fmt.Errorf("%s %s: %d response: %w", method, path, status, err)
+	}
+	return nil
+}
+
+// validateValue checks decoded JSON value against schema, at is the location of value used in errors
+func (s *Spec) validateValue(schema *Schema, value interface{}, at string) error {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+	for _, part := range schema.AllOf {
+		if err := s.validateValue(part, value, at); err != nil {
+			return err
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", at, value)
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: required property %q is missing", at, name)
+			}
+		}
+		for name, property := range object {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fmt.Errorf("%s: property %q is not described", at, name)
+				}
+				continue
+			}
+			if err := s.validateValue(propertySchema, property, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", at, value)
+		}
+		for i, item := range items {
+			if err := s.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected %s, got %T", at, schema.Type, value)
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected integer, got %v", at, n)
+		}
+		if err := checkRange(schema, n); err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", at, value)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", at, value)
+		}
+		if err := checkString(schema, str); err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+		if err := checkFormat(schema, str); err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+	}
+	return nil
+}
+
+// resolve follows $ref of schema to components
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.Components.Schemas[refName(schema.Ref)]
+	}
+	return schema
+}
+
+// checkRange checks minimum and maximum of a number
+func checkRange(schema *Schema, n float64) error {
+	if schema.Minimum != nil && n < *schema.Minimum {
+		return fmt.Errorf("%v is less than %v", n, *schema.Minimum)
+	}
+	if schema.Maximum != nil && n > *schema.Maximum {
+		return fmt.Errorf("%v is greater than %v", n, *schema.Maximum)
+	}
+	return nil
+}
+
+// checkString checks enum and pattern of a string
+func checkString(schema *Schema, value string) error {
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%q is not one of %v", value, schema.Enum)
+		}
+	}
+	if schema.Pattern != "" {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("%q does not match %s", value, schema.Pattern)
+		}
+	}
+	return nil
+}
+
+// checkFormat checks date and date-time formats of a string. It is only used for responses:
+// a well-formed but impossible date in a request is reported by the repository as invalid date
+func checkFormat(schema *Schema, value string) error {
+	switch schema.Format {
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("%q is not a date", value)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%q is not RFC 3339 date-time", value)
+		}
+	}
+	return nil
+}
+
+// refName returns the last part of local reference like #/components/schemas/City
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
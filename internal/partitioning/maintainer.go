@@ -0,0 +1,56 @@
+package partitioning
+
+import (
+	"context"
+	"log"
+	"time"
+	"weather_service/internal/database"
+)
+
+// Maintainer - struct for creating monthly partitions {monthsAhead} months ahead every {interval} seconds
+type Maintainer struct {
+	partitions  database.PartitionManager
+	monthsAhead int
+	ticker      *time.Ticker
+}
+
+// NewMaintainer - constructor for Maintainer struct
+func NewMaintainer(partitions database.PartitionManager, monthsAhead int, interval time.Duration) *Maintainer {
+	return &Maintainer{
+		partitions:  partitions,
+		monthsAhead: monthsAhead,
+		ticker:      time.NewTicker(interval),
+	}
+}
+
+// CreatePartitions makes sure partitions exist from the current month to {monthsAhead} months ahead
+func (m *Maintainer) CreatePartitions() {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, m.monthsAhead, 0)
+
+	if err := m.partitions.CreatePartitions(context.Background(), from, to); err != nil {
+		log.Println("Can not create partitions: error", err)
+		return
+	}
+	log.Println("Partitions created up to", to.Format("2006-01"))
+}
+
+// Start - creates partitions now and then in background with {interval}
+func (m *Maintainer) Start() {
+	m.CreatePartitions()
+	go func() {
+		defer m.ticker.Stop()
+		for {
+			select {
+			case <-m.ticker.C:
+				m.CreatePartitions()
+			}
+		}
+	}()
+}
+
+// Stop - stops maintainer
+func (m *Maintainer) Stop() {
+	m.ticker.Stop()
+}
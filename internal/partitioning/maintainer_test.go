@@ -0,0 +1,53 @@
+package partitioning
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakePartitions records months requested by CreatePartitions
+type fakePartitions struct {
+	from, to []time.Time
+	err      error
+}
+
+func (p *fakePartitions) CreatePartitions(ctx context.Context, from, to time.Time) error {
+	p.from = append(p.from, from)
+	p.to = append(p.to, to)
+	return p.err
+}
+
+func (p *fakePartitions) DropForecastPartitions(ctx context.Context, before time.Time) (int, error) {
+	return 0, errors.New("not expected")
+}
+
+func (p *fakePartitions) DropForecastHistoryPartitions(ctx context.Context, before time.Time) (int, error) {
+	return 0, errors.New("not expected")
+}
+
+func TestCreatePartitions(t *testing.T) {
+	partitions := &fakePartitions{}
+	maintainer := NewMaintainer(partitions, 3, time.Hour)
+	defer maintainer.Stop()
+
+	now := time.Now().UTC()
+	maintainer.CreatePartitions()
+
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if len(partitions.from) != 1 {
+		t.Fatalf("Expected one request, got %d", len(partitions.from))
+	}
+	// one request covers forecasts and forecast_history
+	if !partitions.from[0].Equal(month) || !partitions.to[0].Equal(month.AddDate(0, 3, 0)) {
+		t.Errorf("Expected months from %s to %s, got %s to %s", month, month.AddDate(0, 3, 0), partitions.from[0], partitions.to[0])
+	}
+
+	// failure is logged and the next run tries again
+	partitions.err = errors.New("connection refused")
+	maintainer.CreatePartitions()
+	if len(partitions.from) != 2 {
+		t.Errorf("Expected the second request, got %d", len(partitions.from))
+	}
+}
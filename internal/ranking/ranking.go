@@ -0,0 +1,205 @@
+package ranking
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"weather_service/internal/models"
+)
+
+// Metrics cities can be ranked by, MetricScore is the weighted combination of the others
+const (
+	MetricTemp          = "temp"
+	MetricPrecipitation = "precipitation"
+	MetricWind          = "wind"
+	MetricSunshine      = "sunshine"
+	MetricScore         = "score"
+)
+
+// higherIsBetter - direction of every metric in which a city is better for a trip, it sets default order
+// and the direction of the metric in a weighted score
+var higherIsBetter = map[string]bool{
+	MetricTemp:          true,
+	MetricPrecipitation: false,
+	MetricWind:          false,
+	MetricSunshine:      true,
+	MetricScore:         true,
+}
+
+// Metrics represents weather of a city aggregated over 3-hour slots of a time range
+type Metrics struct {
+	// Temp - mean temperature in Celsius
+	Temp float64 `json:"temp"`
+	// Precipitation - mean rain and snow per day in mm, so cities with missing slots do not look drier
+	Precipitation float64 `json:"precipitation"`
+	// Wind - mean wind speed in m/s
+	Wind float64 `json:"wind"`
+	// Sunshine - mean share of clear sky in daytime slots from 0 to 1, proxy of sunshine from cloud cover
+	Sunshine float64 `json:"sunshine"`
+}
+
+// value returns aggregated value of a metric other than MetricScore
+func (m Metrics) value(metric string) float64 {
+	switch metric {
+	case MetricTemp:
+		return m.Temp
+	case MetricPrecipitation:
+		return m.Precipitation
+	case MetricWind:
+		return m.Wind
+	default:
+		return m.Sunshine
+	}
+}
+
+// Entry represents place of a city in ranking, Value is the ranked metric
+type Entry struct {
+	Rank    int     `json:"rank"`
+	CityID  int     `json:"city_id"`
+	Name    string  `json:"name"`
+	Country string  `json:"country"`
+	Value   float64 `json:"value"`
+	Slots   int     `json:"slots"`
+	Metrics Metrics `json:"metrics"`
+}
+
+// ValidMetric reports whether cities can be ranked by metric
+func ValidMetric(metric string) bool {
+	_, ok := higherIsBetter[metric]
+	return ok
+}
+
+// Descending reports whether metric is ranked from the highest value by default
+func Descending(metric string) bool {
+	return higherIsBetter[metric]
+}
+
+// ParseWeights parses weights of a score like "temp:2,precipitation:1", weights must be positive
+func ParseWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(value, ",") {
+		metric, weightValue, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid weight %q: expected metric:weight", part)
+		}
+		if metric == MetricScore || !ValidMetric(metric) {
+			return nil, fmt.Errorf("invalid weight %q: unknown metric %q", part, metric)
+		}
+		weight, err := strconv.ParseFloat(weightValue, 64)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("invalid weight %q: expected positive number", part)
+		}
+		weights[metric] = weight
+	}
+	return weights, nil
+}
+
+// slotsPerDay - number of 3-hour slots in a day
+const slotsPerDay = 8
+
+// Aggregate calculates metrics of slots, sunshine counts slots that are not at night
+func Aggregate(slots []models.List) Metrics {
+	var m Metrics
+	if len(slots) == 0 {
+		return m
+	}
+
+	var daySlots int
+	var clearSky float64
+	for _, l := range slots {
+		m.Temp += l.Main.Temp
+		m.Precipitation += l.Precipitation3h()
+		m.Wind += l.Wind.Speed
+		if l.Sys.Pod != "n" {
+			daySlots++
+			clearSky += 1 - float64(l.Clouds.All)/100
+		}
+	}
+	m.Temp /= float64(len(slots))
+	m.Precipitation = m.Precipitation / float64(len(slots)) * slotsPerDay
+	m.Wind /= float64(len(slots))
+	if daySlots > 0 {
+		m.Sunshine = clearSky / float64(daySlots)
+	}
+	return m
+}
+
+// Rank orders cities with slots by metric, cities without slots are left out. For MetricScore every weighted metric
+// is scaled to 0..1 across the cities with 1 for the best city, score is the weighted mean of the scaled values.
+// Ties keep the order of cities
+func Rank(cities []models.City, slots map[int][]models.List, metric string, weights map[string]float64, descending bool) []Entry {
+	entries := make([]Entry, 0, len(cities))
+	for _, city := range cities {
+		if len(slots[city.ID]) == 0 {
+			continue
+		}
+		metrics := Aggregate(slots[city.ID])
+		entries = append(entries, Entry{
+			CityID:  city.ID,
+			Name:    city.Name,
+			Country: city.Country,
+			Slots:   len(slots[city.ID]),
+			Metrics: metrics,
+		})
+	}
+
+	if metric == MetricScore {
+		score(entries, weights)
+	} else {
+		for i := range entries {
+			entries[i].Value = entries[i].Metrics.value(metric)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if descending {
+			return entries[i].Value > entries[j].Value
+		}
+		return entries[i].Value < entries[j].Value
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// score sets Value of entries to the weighted mean of metrics scaled across entries
+func score(entries []Entry, weights map[string]float64) {
+	var totalWeight float64
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return
+	}
+
+	// metrics are summed in fixed order, so equal inputs give equal scores
+	for _, metric := range []string{MetricTemp, MetricPrecipitation, MetricWind, MetricSunshine} {
+		weight, ok := weights[metric]
+		if !ok {
+			continue
+		}
+		lowest, highest := 0.0, 0.0
+		for i, e := range entries {
+			v := e.Metrics.value(metric)
+			if i == 0 || v < lowest {
+				lowest = v
+			}
+			if i == 0 || v > highest {
+				highest = v
+			}
+		}
+		for i := range entries {
+			// every city is the best when they do not differ
+			scaled := 1.0
+			if highest > lowest {
+				scaled = (entries[i].Metrics.value(metric) - lowest) / (highest - lowest)
+				if !higherIsBetter[metric] {
+					scaled = 1 - scaled
+				}
+			}
+			entries[i].Value += weight * scaled / totalWeight
+		}
+	}
+}
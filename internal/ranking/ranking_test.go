@@ -0,0 +1,95 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+	"weather_service/internal/models"
+)
+
+func slot(temp, rain, wind float64, clouds int, pod string) models.List {
+	return models.List{
+		Main:   models.Main{Temp: temp},
+		Rain:   &models.Precipitation{ThreeHour: rain},
+		Wind:   models.Wind{Speed: wind},
+		Clouds: models.Clouds{All: clouds},
+		Sys:    models.Sys{Pod: pod},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	m := Aggregate([]models.List{
+		slot(20, 1, 2, 20, "d"),
+		slot(24, 0.5, 4, 60, "d"),
+		slot(13, 0, 3, 100, "n"),
+	})
+	// 1.5 mm in 3 slots is 4 mm per day
+	if m.Temp != 19 || m.Precipitation != 4 || m.Wind != 3 {
+		t.Errorf("Expected temp 19, precipitation 4, wind 3, got %+v", m)
+	}
+	// night is not counted in sunshine
+	if math.Abs(m.Sunshine-0.6) > 1e-9 {
+		t.Errorf("Expected sunshine 0.6, got %v", m.Sunshine)
+	}
+}
+
+func TestRank(t *testing.T) {
+	cities := []models.City{{ID: 1, Name: "Berlin"}, {ID: 2, Name: "Rome"}, {ID: 3, Name: "London"}, {ID: 4, Name: "Oslo"}}
+	slots := map[int][]models.List{
+		1: {slot(20, 0, 5, 50, "d")},
+		2: {slot(30, 4, 2, 10, "d")},
+		3: {slot(15, 1, 5, 90, "d")},
+	}
+
+	ranked := Rank(cities, slots, MetricTemp, nil, Descending(MetricTemp))
+	if len(ranked) != 3 {
+		t.Fatalf("Expected cities without slots to be left out, got %+v", ranked)
+	}
+	if ranked[0].Name != "Rome" || ranked[0].Rank != 1 || ranked[2].Name != "London" || ranked[2].Value != 15 {
+		t.Errorf("Expected Rome, Berlin, London by temperature, got %+v", ranked)
+	}
+
+	ranked = Rank(cities, slots, MetricPrecipitation, nil, Descending(MetricPrecipitation))
+	if ranked[0].Name != "Berlin" || ranked[1].Name != "London" {
+		t.Errorf("Expected driest Berlin first, got %+v", ranked)
+	}
+
+	// Berlin: temp 0.33, dry 1 -> 0.67; Rome: temp 1, dry 0 -> 0.5; London: temp 0, dry 0.75 -> 0.375
+	ranked = Rank(cities, slots, MetricScore, map[string]float64{MetricTemp: 1, MetricPrecipitation: 1}, true)
+	if ranked[0].Name != "Berlin" || ranked[1].Name != "Rome" || ranked[2].Name != "London" {
+		t.Errorf("Expected Berlin, Rome, London by score, got %+v", ranked)
+	}
+	if math.Abs(ranked[0].Value-2.0/3) > 1e-9 || math.Abs(ranked[2].Value-0.375) > 1e-9 {
+		t.Errorf("Expected scores 0.67 and 0.375, got %v and %v", ranked[0].Value, ranked[2].Value)
+	}
+}
+
+func TestRankUnequalSlots(t *testing.T) {
+	cities := []models.City{{ID: 1, Name: "Berlin"}, {ID: 2, Name: "Rome"}}
+	// Berlin has the whole day with 4 mm, Rome only one slot with 1 mm, 8 mm per day
+	berlin := make([]models.List, 0, 8)
+	for i := 0; i < 8; i++ {
+		berlin = append(berlin, slot(20, 0.5, 3, 50, "d"))
+	}
+	slots := map[int][]models.List{1: berlin, 2: {slot(20, 1, 3, 50, "d")}}
+
+	ranked := Rank(cities, slots, MetricPrecipitation, nil, Descending(MetricPrecipitation))
+	if ranked[0].Name != "Berlin" || ranked[0].Value != 4 || ranked[1].Value != 8 {
+		t.Errorf("Expected Berlin drier per day than Rome with missing slots, got %+v", ranked)
+	}
+	ranked = Rank(cities, slots, MetricScore, map[string]float64{MetricPrecipitation: 1}, true)
+	if ranked[0].Name != "Berlin" {
+		t.Errorf("Expected Berlin to score better, got %+v", ranked)
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := ParseWeights("temp:2, precipitation:0.5")
+	if err != nil || weights[MetricTemp] != 2 || weights[MetricPrecipitation] != 0.5 || len(weights) != 2 {
+		t.Errorf("Expected temp 2 and precipitation 0.5, got %v, %v", weights, err)
+	}
+	for _, value := range []string{"temp", "humidity:1", "score:1", "wind:0", "wind:-1", "sunshine:much"} {
+		if _, err := ParseWeights(value); err == nil {
+			t.Errorf("%s: expected error", value)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+	"weather_service/internal/database"
+)
+
+// purgeFunc removes one batch of rows older than before and returns how many rows were affected
+type purgeFunc func(ctx context.Context, before time.Time, limit int) (int64, error)
+
+// Result - number of rows removed by one purge run, rows of dropped partitions are not counted
+type Result struct {
+	SlotsPurged       int64
+	HistoryPurged     int64
+	ForecastsPurged   int64
+	SummariesPurged   int64
+	PartitionsDropped int
+}
+
+// Purger - struct for removing old forecast data every {interval} seconds
+type Purger struct {
+	repo      database.Repository
+	rawDays   int
+	dailyDays int
+	batchSize int
+	ticker    *time.Ticker
+}
+
+// NewPurger - constructor for Purger struct, rawDays and dailyDays equal to 0 disable the policy
+func NewPurger(repo database.Repository, rawDays, dailyDays, batchSize int, interval time.Duration) *Purger {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &Purger{
+		repo:      repo,
+		rawDays:   rawDays,
+		dailyDays: dailyDays,
+		batchSize: batchSize,
+		ticker:    time.NewTicker(interval),
+	}
+}
+
+// Start - starts purger in background with {interval}
+func (p *Purger) Start() {
+	go func() {
+		defer p.ticker.Stop()
+		for {
+			select {
+			case <-p.ticker.C:
+				p.Purge()
+			}
+		}
+	}()
+}
+
+// Purge removes 3-hour slots and issued forecast history older than rawDays, whole forecasts and daily summaries older than dailyDays.
+// When repository keeps them in monthly partitions, fully expired partitions are dropped first
+// and only the partition holding the cutoff is purged row by row
+func (p *Purger) Purge() Result {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	var result Result
+	var err error
+	if partitions, ok := p.repo.(database.PartitionManager); ok {
+		result.PartitionsDropped = p.dropPartitions(ctx, partitions, now)
+	}
+	if p.rawDays > 0 {
+		result.SlotsPurged, err = p.purge(ctx, p.repo.PurgeForecastSlots, cutoff(now, p.rawDays))
+		if err != nil {
+			log.Println("Can not purge forecast slots: error", err)
+		}
+		result.HistoryPurged, err = p.purge(ctx, p.repo.PurgeForecastHistory, cutoff(now, p.rawDays))
+		if err != nil {
+			log.Println("Can not purge forecast history: error", err)
+		}
+	}
+	if p.dailyDays > 0 {
+		result.ForecastsPurged, err = p.purge(ctx, p.repo.PurgeForecasts, cutoff(now, p.dailyDays))
+		if err != nil {
+			log.Println("Can not purge forecasts: error", err)
+		}
+		result.SummariesPurged, err = p.purge(ctx, p.repo.PurgeDailySummaries, cutoff(now, p.dailyDays))
+		if err != nil {
+			log.Println("Can not purge daily summaries: error", err)
+		}
+	}
+
+	log.Printf("Retention purge finished: %d partitions dropped, %d forecast slots cleared, %d issued slots deleted, %d forecasts deleted, %d daily summaries deleted",
+		result.PartitionsDropped, result.SlotsPurged, result.HistoryPurged, result.ForecastsPurged, result.SummariesPurged)
+	return result
+}
+
+// dropPartitions drops partitions of issued history older than rawDays and of forecasts older than dailyDays
+func (p *Purger) dropPartitions(ctx context.Context, partitions database.PartitionManager, now time.Time) int {
+	var dropped int
+	if p.rawDays > 0 {
+		n, err := partitions.DropForecastHistoryPartitions(ctx, cutoff(now, p.rawDays))
+		if err != nil {
+			log.Println("Can not drop forecast history partitions: error", err)
+		}
+		dropped += n
+	}
+	if p.dailyDays > 0 {
+		n, err := partitions.DropForecastPartitions(ctx, cutoff(now, p.dailyDays))
+		if err != nil {
+			log.Println("Can not drop forecast partitions: error", err)
+		}
+		dropped += n
+	}
+	return dropped
+}
+
+// purge runs fn in batches of batchSize until less than a full batch is affected,
+// so each statement only holds its locks for a short time
+func (p *Purger) purge(ctx context.Context, fn purgeFunc, before time.Time) (int64, error) {
+	var total int64
+	for {
+		n, err := fn(ctx, before, p.batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < int64(p.batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// Stop - stops purger
+func (p *Purger) Stop() {
+	p.ticker.Stop()
+}
+
+// cutoff returns midnight {days} days before now
+func cutoff(now time.Time, days int) time.Time {
+	return now.Truncate(24*time.Hour).AddDate(0, 0, -days)
+}
@@ -0,0 +1,159 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"weather_service/internal/database"
+	"weather_service/internal/database/memory"
+	"weather_service/internal/models"
+)
+
+// recordingRepo records cutoffs of purge calls and returns batches from counts, 0 when they run out
+type recordingRepo struct {
+	database.Repository
+	counts  []int64
+	befores map[string][]time.Time
+}
+
+func newRecordingRepo(counts ...int64) *recordingRepo {
+	return &recordingRepo{Repository: memory.NewMemoryRepository(), counts: counts, befores: make(map[string][]time.Time)}
+}
+
+func (r *recordingRepo) batch(name string, before time.Time) (int64, error) {
+	r.befores[name] = append(r.befores[name], before)
+	if len(r.counts) == 0 {
+		return 0, nil
+	}
+	n := r.counts[0]
+	r.counts = r.counts[1:]
+	return n, nil
+}
+
+func (r *recordingRepo) PurgeForecastSlots(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.batch("slots", before)
+}
+
+func (r *recordingRepo) PurgeForecastHistory(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.batch("history", before)
+}
+
+func (r *recordingRepo) PurgeForecasts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.batch("forecasts", before)
+}
+
+func (r *recordingRepo) PurgeDailySummaries(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.batch("summaries", before)
+}
+
+// partitionedRepo is recordingRepo keeping forecasts and history in partitions
+type partitionedRepo struct {
+	*recordingRepo
+	err error
+}
+
+func (r *partitionedRepo) CreatePartitions(ctx context.Context, from, to time.Time) error {
+	return errors.New("not expected")
+}
+
+func (r *partitionedRepo) DropForecastPartitions(ctx context.Context, before time.Time) (int, error) {
+	r.befores["forecast partitions"] = append(r.befores["forecast partitions"], before)
+	return 2, nil
+}
+
+func (r *partitionedRepo) DropForecastHistoryPartitions(ctx context.Context, before time.Time) (int, error) {
+	r.befores["history partitions"] = append(r.befores["history partitions"], before)
+	return 1, r.err
+}
+
+func TestPurgeCutoffs(t *testing.T) {
+	repo := newRecordingRepo()
+	purger := NewPurger(repo, 7, 30, 100, time.Hour)
+	defer purger.Stop()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	purger.Purge()
+
+	raw, daily := today.AddDate(0, 0, -7), today.AddDate(0, 0, -30)
+	for name, expected := range map[string]time.Time{"slots": raw, "history": raw, "forecasts": daily, "summaries": daily} {
+		if len(repo.befores[name]) != 1 || !repo.befores[name][0].Equal(expected) {
+			t.Errorf("%s: expected one purge before %s, got %v", name, expected, repo.befores[name])
+		}
+	}
+
+	// disabled policies purge nothing
+	repo = newRecordingRepo()
+	NewPurger(repo, 0, 30, 100, time.Hour).Purge()
+	if len(repo.befores["slots"]) != 0 || len(repo.befores["history"]) != 0 || len(repo.befores["forecasts"]) != 1 {
+		t.Errorf("Expected only daily policy, got %v", repo.befores)
+	}
+}
+
+func TestPurgeBatches(t *testing.T) {
+	// two full batches of slots, then a short one stops the loop
+	repo := newRecordingRepo(10, 10, 3)
+	result := NewPurger(repo, 7, 0, 10, time.Hour).Purge()
+
+	if result.SlotsPurged != 23 || len(repo.befores["slots"]) != 3 {
+		t.Errorf("Expected 23 slots in 3 batches, got %d in %d", result.SlotsPurged, len(repo.befores["slots"]))
+	}
+	// history gets an empty batch and stops at once
+	if result.HistoryPurged != 0 || len(repo.befores["history"]) != 1 {
+		t.Errorf("Expected one empty history batch, got %d in %d", result.HistoryPurged, len(repo.befores["history"]))
+	}
+	// disabled daily policy purges no forecasts
+	if result.ForecastsPurged != 0 || len(repo.befores["forecasts"]) != 0 {
+		t.Errorf("Expected no forecast batches, got %d in %d", result.ForecastsPurged, len(repo.befores["forecasts"]))
+	}
+}
+
+func TestPurgePartitions(t *testing.T) {
+	repo := &partitionedRepo{recordingRepo: newRecordingRepo(), err: errors.New("lock timeout")}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	result := NewPurger(repo, 7, 30, 100, time.Hour).Purge()
+
+	// failure of one table is logged and the other partitions still count
+	if result.PartitionsDropped != 3 {
+		t.Errorf("Expected 3 partitions dropped, got %d", result.PartitionsDropped)
+	}
+	if before := repo.befores["history partitions"]; len(before) != 1 || !before[0].Equal(today.AddDate(0, 0, -7)) {
+		t.Errorf("Expected history partitions dropped before raw cutoff, got %v", before)
+	}
+	if before := repo.befores["forecast partitions"]; len(before) != 1 || !before[0].Equal(today.AddDate(0, 0, -30)) {
+		t.Errorf("Expected forecast partitions dropped before daily cutoff, got %v", before)
+	}
+	// the partition holding the cutoff is still purged row by row
+	if len(repo.befores["slots"]) != 1 || len(repo.befores["forecasts"]) != 1 {
+		t.Errorf("Expected row purge after dropping partitions, got %v", repo.befores)
+	}
+}
+
+func TestPurgeMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	city := models.City{Name: "Berlin", Country: "DE"}
+	if err := repo.CreateCity(ctx, &city); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, days := range []int{40, 10, 0} {
+		forecast := models.WeatherInfo{Temp: 20, Date: today.AddDate(0, 0, -days), AdditionalInfo: []models.List{{Dt: 1}}}
+		if err := repo.CreateForecast(ctx, &forecast, city.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// batch of 1 row takes a call per row
+	result := NewPurger(repo, 7, 30, 1, time.Hour).Purge()
+	if result.SlotsPurged != 2 || result.ForecastsPurged != 1 {
+		t.Errorf("Expected slots of 2 old days cleared and 1 forecast deleted, got %+v", result)
+	}
+	if _, err := repo.GetForecastByCityIDandDate(ctx, city.ID, today.AddDate(0, 0, -40).Format("2006-01-02")); !errors.Is(err, database.ErrForecastNotFound) {
+		t.Errorf("Expected forecast older than 30 days to be deleted, got %v", err)
+	}
+	forecasts, err := repo.GetForecastByCityIDandDate(ctx, city.ID, today.AddDate(0, 0, -10).Format("2006-01-02"))
+	if err != nil || len(forecasts) != 1 || len(forecasts[0].AdditionalInfo) != 0 {
+		t.Errorf("Expected forecast of 10 days ago without slots, got %+v, %v", forecasts, err)
+	}
+}
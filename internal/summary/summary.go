@@ -0,0 +1,115 @@
+package summary
+
+import (
+	"math"
+	"sort"
+	"time"
+	"weather_service/internal/models"
+)
+
+// slotsPerDay - number of 3-hour slots covering a whole local day
+const slotsPerDay = 8
+
+// Summarize groups forecast slots (in Celsius) of a city by local calendar day for utcOffset seconds
+// and aggregates every day. A forecast run starts at the next 3-hour boundary, so today and the last day
+// of the run are covered by less than 8 slots and marked partial. Summaries are ordered by date
+func Summarize(cityID int, slots []models.List, utcOffset int) []models.DailySummary {
+	offset := time.Duration(utcOffset) * time.Second
+	days := make(map[time.Time][]models.List)
+	for _, slot := range slots {
+		local := slot.DtTime.UTC().Add(offset)
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		days[date] = append(days[date], slot)
+	}
+
+	summaries := make([]models.DailySummary, 0, len(days))
+	for date, daySlots := range days {
+		summary := summarizeDay(date, daySlots, offset)
+		summary.CityID = cityID
+		summary.UTCOffset = utcOffset
+		summary.Partial = len(daySlots) < slotsPerDay
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Date.Before(summaries[j].Date)
+	})
+	return summaries
+}
+
+// summarizeDay aggregates slots of one local day
+func summarizeDay(date time.Time, slots []models.List, offset time.Duration) models.DailySummary {
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].DtTime.Before(slots[j].DtTime)
+	})
+
+	summary := models.DailySummary{
+		Date:    date,
+		TempMin: math.Inf(1),
+		TempMax: math.Inf(-1),
+	}
+	midday := date.Add(12 * time.Hour)
+	middayDistance := time.Duration(math.MaxInt64)
+	conditions := make(map[string]int)
+	var sum float64
+	for _, slot := range slots {
+		temp := slot.Main.Temp
+		summary.TempMin = math.Min(summary.TempMin, temp)
+		summary.TempMax = math.Max(summary.TempMax, temp)
+		sum += temp
+		summary.Precipitation += slot.Precipitation3h()
+		summary.WindMax = math.Max(summary.WindMax, slot.Wind.Speed)
+		summary.GustMax = math.Max(summary.GustMax, slot.Wind.Gust)
+		summary.PopMax = math.Max(summary.PopMax, slot.Pop)
+
+		// slot nearest to local noon, the earlier one wins a tie
+		if distance := absDuration(slot.DtTime.UTC().Add(offset).Sub(midday)); distance < middayDistance {
+			middayDistance = distance
+			summary.TempMidday = temp
+		}
+		if len(slot.Weather) > 0 {
+			conditions[slot.Weather[0].Main]++
+		}
+	}
+	summary.TempMean = sum / float64(len(slots))
+	summary.Condition = dominant(slots, conditions)
+	summary.Icon = icon(slots, summary.Condition, midday, offset)
+	return summary
+}
+
+// dominant returns the most frequent condition, the one seen first wins a tie
+func dominant(slots []models.List, conditions map[string]int) string {
+	var condition string
+	for _, slot := range slots {
+		if len(slot.Weather) == 0 {
+			continue
+		}
+		if main := slot.Weather[0].Main; conditions[main] > conditions[condition] {
+			condition = main
+		}
+	}
+	return condition
+}
+
+// icon returns icon of the slot with condition nearest to local midday, so a daytime icon is preferred
+func icon(slots []models.List, condition string, midday time.Time, offset time.Duration) string {
+	var icon string
+	distance := time.Duration(math.MaxInt64)
+	for _, slot := range slots {
+		if len(slot.Weather) == 0 || slot.Weather[0].Main != condition {
+			continue
+		}
+		if d := absDuration(slot.DtTime.UTC().Add(offset).Sub(midday)); d < distance {
+			distance = d
+			icon = slot.Weather[0].Icon
+		}
+	}
+	return icon
+}
+
+// absDuration returns absolute value of d
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,110 @@
+package summary
+
+import (
+	"math"
+	"testing"
+	"time"
+	"weather_service/internal/models"
+)
+
+// slot returns forecast slot valid at UTC time at, its icon is the UTC hour
+func slot(at time.Time, temp float64, condition string) models.List {
+	return models.List{
+		DtTime:  at,
+		Main:    models.Main{Temp: temp},
+		Weather: []models.Weather{{Main: condition, Icon: at.Format("15")}},
+		Wind:    models.Wind{Speed: temp / 10, Gust: temp / 5},
+		Pop:     temp / 100,
+		Rain:    &models.Precipitation{ThreeHour: 0.5},
+	}
+}
+
+func TestSummarizeGroupsByLocalDay(t *testing.T) {
+	day := time.Date(2024, 7, 11, 0, 0, 0, 0, time.UTC)
+	slots := []models.List{
+		slot(day.Add(18*time.Hour), 20, "Clouds"),
+		slot(day.Add(21*time.Hour), 16, "Rain"),
+		slot(day.Add(24*time.Hour), 14, "Rain"),
+		slot(day.Add(9*time.Hour), 24, "Clear"),
+		slot(day.Add(12*time.Hour), 26, "Clouds"),
+		slot(day.Add(15*time.Hour), 25, "Clear"),
+	}
+
+	// UTC+3: 21:00 and 00:00 UTC belong to the next local day
+	summaries := Summarize(7, slots, 3*3600)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 local days, got %+v", summaries)
+	}
+
+	first := summaries[0]
+	if !first.Date.Equal(day) || first.CityID != 7 || first.UTCOffset != 3*3600 {
+		t.Errorf("Expected summary of city 7 for %s, got %+v", day, first)
+	}
+	if first.TempMin != 20 || first.TempMax != 26 || first.TempMean != 23.75 {
+		t.Errorf("Expected min 20, max 26, mean 23.75, got %+v", first)
+	}
+	// local noon is 09:00 UTC
+	if first.TempMidday != 24 {
+		t.Errorf("Expected midday temp of 09:00 UTC slot, got %v", first.TempMidday)
+	}
+	if first.Precipitation != 2 || first.WindMax != 2.6 || first.GustMax != 5.2 || first.PopMax != 0.26 {
+		t.Errorf("Expected totals and maximums of 4 slots, got %+v", first)
+	}
+	// Clear and Clouds are seen twice, Clear comes first
+	if first.Condition != "Clear" {
+		t.Errorf("Expected dominant condition Clear, got %s", first.Condition)
+	}
+	// Clear slots are at 09:00 and 15:00 UTC, 09:00 is local noon
+	if first.Icon != "09" {
+		t.Errorf("Expected icon of Clear slot nearest to noon, got %s", first.Icon)
+	}
+
+	second := summaries[1]
+	if !second.Date.Equal(day.AddDate(0, 0, 1)) || second.Condition != "Rain" || second.TempMean != 15 {
+		t.Errorf("Expected rainy next day with mean 15, got %+v", second)
+	}
+	// only two night slots, 00:00 UTC is nearer to local noon
+	if second.TempMidday != 14 {
+		t.Errorf("Expected midday temp of the nearest slot, got %v", second.TempMidday)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	summaries := Summarize(1, nil, 0)
+	if summaries == nil || len(summaries) != 0 {
+		t.Errorf("Expected empty non-nil list, got %#v", summaries)
+	}
+	if math.IsInf(Summarize(1, []models.List{{DtTime: time.Now()}}, 0)[0].TempMin, 0) {
+		t.Errorf("Expected finite temperatures for a single slot")
+	}
+}
+
+func TestSummarizeMarksPartialDays(t *testing.T) {
+	// run issued at 10:00 UTC starts at 12:00, 40 slots end at 09:00 five days later
+	start := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	slots := make([]models.List, 0, 40)
+	for i := 0; i < 40; i++ {
+		at := start.Add(time.Duration(i) * 3 * time.Hour)
+		slots = append(slots, slot(at, float64(at.Hour()), "Clear"))
+	}
+
+	summaries := Summarize(7, slots, 0)
+	if len(summaries) != 6 {
+		t.Fatalf("Expected 6 days, got %d", len(summaries))
+	}
+	for i, s := range summaries {
+		if partial := i == 0 || i == 5; s.Partial != partial {
+			t.Errorf("Day %s: expected partial %v, got %v", s.Date, partial, s.Partial)
+		}
+	}
+	for _, s := range summaries[1:5] {
+		if s.TempMin != 0 || s.TempMax != 21 || s.TempMidday != 12 {
+			t.Errorf("Expected extremes of the whole day and noon temp, got %+v", s)
+		}
+	}
+
+	// today is summarized from its 4 afternoon slots
+	if today := summaries[0]; today.TempMin != 12 || today.TempMidday != 12 {
+		t.Errorf("Expected today from afternoon slots, got %+v", today)
+	}
+}
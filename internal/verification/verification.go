@@ -0,0 +1,119 @@
+package verification
+
+import (
+	"math"
+	"sort"
+	"time"
+	"weather_service/internal/models"
+)
+
+const (
+	// leadStep - lead times are grouped to the forecast slot length
+	leadStep = 3 * time.Hour
+	// popThreshold - probability of precipitation from which forecast counts as "precipitation expected"
+	popThreshold = 0.5
+)
+
+// wetConditions - observed conditions counted as precipitation when the amount is unknown
+var wetConditions = map[string]bool{
+	"Rain":         true,
+	"Drizzle":      true,
+	"Thunderstorm": true,
+	"Snow":         true,
+}
+
+// Score represents forecast quality of one provider at one lead time
+type Score struct {
+	Provider        string   `json:"provider"`
+	LeadHours       int      `json:"lead_hours"`
+	Samples         int      `json:"samples"`
+	TempMAE         float64  `json:"temp_mae"`
+	TempBias        float64  `json:"temp_bias"`
+	TempRMSE        float64  `json:"temp_rmse"`
+	PrecipSamples   int      `json:"precip_samples"`
+	PrecipHitRate   *float64 `json:"precip_hit_rate"`
+	precipHits      int
+	sumAbsTempError float64
+	sumTempError    float64
+	sumSqTempError  float64
+}
+
+// Report represents verification scores for a city (or all cities when CityID is 0) in a time range
+type Report struct {
+	CityID int       `json:"city_id,omitempty"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Scores []Score   `json:"scores"`
+}
+
+// LeadBucket rounds lead time of the pair to the nearest slot length
+func LeadBucket(lead time.Duration) time.Duration {
+	return lead.Round(leadStep)
+}
+
+// Compute calculates scores per provider and lead time, lead equal to 0 keeps all lead times
+func Compute(pairs []models.VerificationPair, lead time.Duration) []Score {
+	scores := make(map[string]map[time.Duration]*Score)
+	for _, p := range pairs {
+		bucket := LeadBucket(p.Lead())
+		if bucket < 0 || (lead != 0 && bucket != LeadBucket(lead)) {
+			continue
+		}
+		if _, ok := scores[p.Provider]; !ok {
+			scores[p.Provider] = make(map[time.Duration]*Score)
+		}
+		s, ok := scores[p.Provider][bucket]
+		if !ok {
+			s = &Score{Provider: p.Provider, LeadHours: int(bucket / time.Hour)}
+			scores[p.Provider][bucket] = s
+		}
+
+		tempError := p.ForecastTemp - p.ObservedTemp
+		s.Samples++
+		s.sumTempError += tempError
+		s.sumAbsTempError += math.Abs(tempError)
+		s.sumSqTempError += tempError * tempError
+
+		if observed, ok := observedPrecipitation(p); ok {
+			s.PrecipSamples++
+			if (p.ForecastPop >= popThreshold) == observed {
+				s.precipHits++
+			}
+		}
+	}
+
+	result := make([]Score, 0)
+	for _, byLead := range scores {
+		for _, s := range byLead {
+			n := float64(s.Samples)
+			s.TempMAE = s.sumAbsTempError / n
+			s.TempBias = s.sumTempError / n
+			s.TempRMSE = math.Sqrt(s.sumSqTempError / n)
+			if s.PrecipSamples > 0 {
+				hitRate := float64(s.precipHits) / float64(s.PrecipSamples)
+				s.PrecipHitRate = &hitRate
+			}
+			result = append(result, *s)
+		}
+	}
+
+	// sort scores by provider and lead time
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Provider != result[j].Provider {
+			return result[i].Provider < result[j].Provider
+		}
+		return result[i].LeadHours < result[j].LeadHours
+	})
+	return result
+}
+
+// observedPrecipitation reports whether precipitation was observed, false in second value when it is unknown
+func observedPrecipitation(p models.VerificationPair) (bool, bool) {
+	if p.ObservedPrecipitation != nil {
+		return *p.ObservedPrecipitation > 0, true
+	}
+	if p.ObservedCondition != "" {
+		return wetConditions[p.ObservedCondition], true
+	}
+	return false, false
+}
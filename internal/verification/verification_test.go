@@ -0,0 +1,70 @@
+package verification
+
+import (
+	"math"
+	"testing"
+	"time"
+	"weather_service/internal/models"
+)
+
+func pair(provider string, lead time.Duration, forecast, observed, pop float64, precipitation *float64) models.VerificationPair {
+	valid := time.Date(2024, 7, 11, 12, 0, 0, 0, time.UTC)
+	return models.VerificationPair{
+		Provider:              provider,
+		IssuedAt:              valid.Add(-lead),
+		ValidAt:               valid,
+		ForecastTemp:          forecast,
+		ForecastPop:           pop,
+		ObservedAt:            valid,
+		ObservedTemp:          observed,
+		ObservedPrecipitation: precipitation,
+	}
+}
+
+func TestComputeScores(t *testing.T) {
+	wet, dry := 1.2, 0.0
+	pairs := []models.VerificationPair{
+		pair("owm", 24*time.Hour, 22, 20, 0.8, &wet),
+		pair("owm", 23*time.Hour, 18, 20, 0.1, &wet),
+		pair("owm", 24*time.Hour, 21, 20, 0.2, &dry),
+		pair("owm", 48*time.Hour, 25, 20, 0.9, nil),
+	}
+
+	scores := Compute(pairs, 0)
+	if len(scores) != 2 {
+		t.Fatalf("Expected 2 scores, got %d", len(scores))
+	}
+
+	s := scores[0]
+	if s.LeadHours != 24 || s.Samples != 3 {
+		t.Fatalf("Expected 3 samples at 24h, got %d at %dh", s.Samples, s.LeadHours)
+	}
+	if math.Abs(s.TempMAE-5.0/3) > 1e-9 {
+		t.Errorf("Expected MAE 5/3, got %v", s.TempMAE)
+	}
+	if math.Abs(s.TempBias-1.0/3) > 1e-9 {
+		t.Errorf("Expected bias 1/3, got %v", s.TempBias)
+	}
+	if math.Abs(s.TempRMSE-math.Sqrt(3)) > 1e-9 {
+		t.Errorf("Expected RMSE sqrt(3), got %v", s.TempRMSE)
+	}
+	if s.PrecipHitRate == nil || math.Abs(*s.PrecipHitRate-2.0/3) > 1e-9 {
+		t.Errorf("Expected hit rate 2/3, got %v", s.PrecipHitRate)
+	}
+
+	if scores[1].LeadHours != 48 || scores[1].PrecipHitRate != nil {
+		t.Errorf("Expected 48h score without precipitation samples, got %+v", scores[1])
+	}
+}
+
+func TestComputeFiltersLead(t *testing.T) {
+	pairs := []models.VerificationPair{
+		pair("owm", 24*time.Hour, 22, 20, 0, nil),
+		pair("owm", 48*time.Hour, 25, 20, 0, nil),
+	}
+
+	scores := Compute(pairs, 48*time.Hour)
+	if len(scores) != 1 || scores[0].LeadHours != 48 {
+		t.Errorf("Expected only 48h score, got %+v", scores)
+	}
+}
@@ -1,7 +1,6 @@
-DROP TABLE IF EXISTS forecasts;
-DROP TABLE IF EXISTS cities;
+-- runs on every start and never drops stored data: missing tables and indexes are created, older tables are migrated in place
 
-CREATE TABLE cities
+CREATE TABLE IF NOT EXISTS cities
 (
     id SERIAL PRIMARY KEY,
     city CHARACTER VARYING,
@@ -10,16 +9,193 @@ CREATE TABLE cities
     long DECIMAL(9,6)
 );
 
-CREATE TABLE forecasts
+CREATE UNIQUE INDEX IF NOT EXISTS cities_city_country_key ON cities (city, country);
+
+-- keyset pagination of cities list in every sort order
+CREATE INDEX IF NOT EXISTS cities_city_id_idx ON cities (city, id);
+CREATE INDEX IF NOT EXISTS cities_country_city_id_idx ON cities (country, city, id);
+CREATE INDEX IF NOT EXISTS cities_lat_id_idx ON cities (lat, id);
+
+-- creates partition of parent table for the month of month, partitions are named like forecasts_y2024m07
+CREATE OR REPLACE FUNCTION create_monthly_partition(parent TEXT, month DATE) RETURNS VOID AS $$
+DECLARE
+    start DATE := date_trunc('month', month)::date;
+BEGIN
+    -- bounds are written as UTC timestamps, so they suit both DATE and TIMESTAMPTZ partition keys
+    EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+        parent || to_char(start, '"_y"YYYY"m"MM'), parent,
+        to_char(start, 'YYYY-MM-DD') || ' 00:00:00+00',
+        to_char(start + INTERVAL '1 month', 'YYYY-MM-DD') || ' 00:00:00+00');
+END
+$$ LANGUAGE plpgsql;
+
+-- tables created before partitioning are renamed and copied into partitions below,
+-- their primary key and unique constraint are dropped to free index names
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'forecasts' AND relkind = 'r') THEN
+        ALTER TABLE forecasts RENAME TO forecasts_unpartitioned;
+        ALTER TABLE forecasts_unpartitioned DROP CONSTRAINT forecasts_pkey, DROP CONSTRAINT unique_city_date;
+        DROP INDEX IF EXISTS forecasts_date_idx;
+    END IF;
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'forecast_history' AND relkind = 'r') THEN
+        ALTER TABLE forecast_history RENAME TO forecast_history_unpartitioned;
+        ALTER TABLE forecast_history_unpartitioned DROP CONSTRAINT forecast_history_pkey, DROP CONSTRAINT unique_city_provider_issued_valid;
+        DROP INDEX IF EXISTS forecast_history_valid_at_idx;
+    END IF;
+END
+$$;
+
+-- partitioned by month of date, partitions are created ahead by maintenance job
+-- and whole partitions are dropped by retention
+CREATE TABLE IF NOT EXISTS forecasts
 (
-    id SERIAL PRIMARY KEY,
+    id SERIAL,
     temp INT,
-    date DATE,
+    date DATE NOT NULL,
     additional_info JSONB,
     city_id INT,
+    CONSTRAINT forecasts_pkey PRIMARY KEY (id, date),
     CONSTRAINT forecasts_city_id_fkey FOREIGN KEY (city_id)
         REFERENCES cities(id)
         ON DELETE CASCADE
         ON UPDATE CASCADE,
     CONSTRAINT unique_city_date UNIQUE (city_id, date)
+) PARTITION BY RANGE (date);
+
+-- retention job scans forecasts by date
+CREATE INDEX IF NOT EXISTS forecasts_date_idx ON forecasts (date);
+
+-- forecast aggregated by local day of the city, replaced together with forecasts on every update
+CREATE TABLE IF NOT EXISTS daily_summaries
+(
+    city_id INT NOT NULL,
+    date DATE NOT NULL,
+    utc_offset INT NOT NULL DEFAULT 0,
+    temp_min DOUBLE PRECISION NOT NULL,
+    temp_max DOUBLE PRECISION NOT NULL,
+    temp_mean DOUBLE PRECISION NOT NULL,
+    temp_midday DOUBLE PRECISION NOT NULL,
+    precipitation DOUBLE PRECISION NOT NULL DEFAULT 0,
+    wind_max DOUBLE PRECISION NOT NULL DEFAULT 0,
+    gust_max DOUBLE PRECISION NOT NULL DEFAULT 0,
+    pop_max DOUBLE PRECISION NOT NULL DEFAULT 0,
+    condition CHARACTER VARYING NOT NULL DEFAULT '',
+    icon CHARACTER VARYING NOT NULL DEFAULT '',
+    -- day is not covered by all 8 slots of the run, it does not replace a summary of a whole day
+    partial BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT daily_summaries_pkey PRIMARY KEY (city_id, date),
+    CONSTRAINT daily_summaries_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+ALTER TABLE daily_summaries
+    ADD COLUMN IF NOT EXISTS gust_max DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS icon CHARACTER VARYING NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS partial BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS daily_summaries_date_idx ON daily_summaries (date);
+
+CREATE TABLE IF NOT EXISTS observations
+(
+    id SERIAL PRIMARY KEY,
+    city_id INT NOT NULL,
+    observed_at TIMESTAMPTZ NOT NULL,
+    source CHARACTER VARYING NOT NULL,
+    temp DOUBLE PRECISION NOT NULL,
+    humidity INT,
+    pressure INT,
+    wind_speed DOUBLE PRECISION,
+    wind_deg INT,
+    clouds INT,
+    precipitation DOUBLE PRECISION,
+    condition CHARACTER VARYING,
+    CONSTRAINT observations_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    CONSTRAINT unique_city_observed_at_source UNIQUE (city_id, observed_at, source)
+);
+
+-- forecasts as they were issued, one row per provider run and valid time, partitioned by month of valid time
+CREATE TABLE IF NOT EXISTS forecast_history
+(
+    id BIGSERIAL,
+    city_id INT NOT NULL,
+    provider CHARACTER VARYING NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL,
+    valid_at TIMESTAMPTZ NOT NULL,
+    temp DOUBLE PRECISION NOT NULL,
+    pop DOUBLE PRECISION NOT NULL,
+    precipitation DOUBLE PRECISION NOT NULL DEFAULT 0,
+    CONSTRAINT forecast_history_pkey PRIMARY KEY (id, valid_at),
+    CONSTRAINT forecast_history_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    CONSTRAINT unique_city_provider_issued_valid UNIQUE (city_id, provider, issued_at, valid_at)
+) PARTITION BY RANGE (valid_at);
+
+CREATE INDEX IF NOT EXISTS forecast_history_valid_at_idx ON forecast_history (valid_at);
+
+-- partitions for the current and the next month, so writes work before maintenance job runs
+SELECT create_monthly_partition('forecasts', CURRENT_DATE);
+SELECT create_monthly_partition('forecasts', (CURRENT_DATE + INTERVAL '1 month')::date);
+SELECT create_monthly_partition('forecast_history', CURRENT_DATE);
+SELECT create_monthly_partition('forecast_history', (CURRENT_DATE + INTERVAL '1 month')::date);
+
+-- copy rows of tables created before partitioning, ids are kept
+DO $$
+DECLARE
+    month DATE;
+BEGIN
+    IF to_regclass('forecasts_unpartitioned') IS NOT NULL THEN
+        FOR month IN SELECT DISTINCT date_trunc('month', date)::date FROM forecasts_unpartitioned WHERE date IS NOT NULL LOOP
+            PERFORM create_monthly_partition('forecasts', month);
+        END LOOP;
+        INSERT INTO forecasts (id, temp, date, additional_info, city_id)
+        SELECT id, temp, date, additional_info, city_id FROM forecasts_unpartitioned WHERE date IS NOT NULL;
+        PERFORM setval(pg_get_serial_sequence('forecasts', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM forecasts;
+        DROP TABLE forecasts_unpartitioned;
+    END IF;
+    IF to_regclass('forecast_history_unpartitioned') IS NOT NULL THEN
+        FOR month IN SELECT DISTINCT date_trunc('month', valid_at AT TIME ZONE 'UTC')::date FROM forecast_history_unpartitioned LOOP
+            PERFORM create_monthly_partition('forecast_history', month);
+        END LOOP;
+        INSERT INTO forecast_history (id, city_id, provider, issued_at, valid_at, temp, pop, precipitation)
+        SELECT id, city_id, provider, issued_at, valid_at, temp, pop, precipitation FROM forecast_history_unpartitioned;
+        PERFORM setval(pg_get_serial_sequence('forecast_history', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM forecast_history;
+        DROP TABLE forecast_history_unpartitioned;
+    END IF;
+END
+$$;
+
+-- trained temperature corrections, rows are never updated so every version can be reproduced
+CREATE TABLE IF NOT EXISTS bias_corrections
+(
+    version SERIAL PRIMARY KEY,
+    trained_at TIMESTAMPTZ NOT NULL,
+    window_from TIMESTAMPTZ NOT NULL,
+    window_to TIMESTAMPTZ NOT NULL,
+    min_samples INT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bias_correction_offsets
+(
+    version INT NOT NULL,
+    city_id INT NOT NULL,
+    hour INT NOT NULL,
+    lead_hours INT NOT NULL,
+    bias DOUBLE PRECISION NOT NULL,
+    samples INT NOT NULL,
+    CONSTRAINT bias_correction_offsets_pkey PRIMARY KEY (version, city_id, hour, lead_hours),
+    CONSTRAINT bias_correction_offsets_version_fkey FOREIGN KEY (version)
+        REFERENCES bias_corrections(version)
+        ON DELETE CASCADE,
+    CONSTRAINT bias_correction_offsets_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
 );
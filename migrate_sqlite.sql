@@ -0,0 +1,127 @@
+-- dates are stored as TEXT YYYY-MM-DD, timestamps as INTEGER unix microseconds (UTC)
+
+CREATE TABLE IF NOT EXISTS cities
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    city TEXT,
+    country TEXT,
+    lat REAL,
+    long REAL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS cities_city_country_key ON cities (city, country);
+
+-- keyset pagination of cities list in every sort order
+CREATE INDEX IF NOT EXISTS cities_city_id_idx ON cities (city, id);
+CREATE INDEX IF NOT EXISTS cities_country_city_id_idx ON cities (country, city, id);
+CREATE INDEX IF NOT EXISTS cities_lat_id_idx ON cities (lat, id);
+
+CREATE TABLE IF NOT EXISTS forecasts
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    temp INTEGER,
+    date TEXT,
+    additional_info TEXT,
+    city_id INTEGER,
+    CONSTRAINT forecasts_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    CONSTRAINT unique_city_date UNIQUE (city_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS forecasts_date_idx ON forecasts (date);
+
+-- forecast aggregated by local day of the city, replaced together with forecasts on every update,
+-- gust_max, icon and partial are added to older database files by pkg/client/sqlite.go
+CREATE TABLE IF NOT EXISTS daily_summaries
+(
+    city_id INTEGER NOT NULL,
+    date TEXT NOT NULL,
+    utc_offset INTEGER NOT NULL DEFAULT 0,
+    temp_min REAL NOT NULL,
+    temp_max REAL NOT NULL,
+    temp_mean REAL NOT NULL,
+    temp_midday REAL NOT NULL,
+    precipitation REAL NOT NULL DEFAULT 0,
+    wind_max REAL NOT NULL DEFAULT 0,
+    gust_max REAL NOT NULL DEFAULT 0,
+    pop_max REAL NOT NULL DEFAULT 0,
+    condition TEXT NOT NULL DEFAULT '',
+    icon TEXT NOT NULL DEFAULT '',
+    partial INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT daily_summaries_pkey PRIMARY KEY (city_id, date),
+    CONSTRAINT daily_summaries_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS daily_summaries_date_idx ON daily_summaries (date);
+
+CREATE TABLE IF NOT EXISTS observations
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    city_id INTEGER NOT NULL,
+    observed_at INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    temp REAL NOT NULL,
+    humidity INTEGER,
+    pressure INTEGER,
+    wind_speed REAL,
+    wind_deg INTEGER,
+    clouds INTEGER,
+    precipitation REAL,
+    condition TEXT,
+    CONSTRAINT observations_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    CONSTRAINT unique_city_observed_at_source UNIQUE (city_id, observed_at, source)
+);
+
+CREATE TABLE IF NOT EXISTS forecast_history
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    city_id INTEGER NOT NULL,
+    provider TEXT NOT NULL,
+    issued_at INTEGER NOT NULL,
+    valid_at INTEGER NOT NULL,
+    temp REAL NOT NULL,
+    pop REAL NOT NULL,
+    precipitation REAL NOT NULL DEFAULT 0,
+    CONSTRAINT forecast_history_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    CONSTRAINT unique_city_provider_issued_valid UNIQUE (city_id, provider, issued_at, valid_at)
+);
+
+CREATE INDEX IF NOT EXISTS forecast_history_valid_at_idx ON forecast_history (valid_at);
+
+CREATE TABLE IF NOT EXISTS bias_corrections
+(
+    version INTEGER PRIMARY KEY AUTOINCREMENT,
+    trained_at INTEGER NOT NULL,
+    window_from INTEGER NOT NULL,
+    window_to INTEGER NOT NULL,
+    min_samples INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bias_correction_offsets
+(
+    version INTEGER NOT NULL,
+    city_id INTEGER NOT NULL,
+    hour INTEGER NOT NULL,
+    lead_hours INTEGER NOT NULL,
+    bias REAL NOT NULL,
+    samples INTEGER NOT NULL,
+    CONSTRAINT bias_correction_offsets_pkey PRIMARY KEY (version, city_id, hour, lead_hours),
+    CONSTRAINT bias_correction_offsets_version_fkey FOREIGN KEY (version)
+        REFERENCES bias_corrections(version)
+        ON DELETE CASCADE,
+    CONSTRAINT bias_correction_offsets_city_id_fkey FOREIGN KEY (city_id)
+        REFERENCES cities(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
@@ -7,11 +7,16 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"time"
 	"weather_service/internal/config"
 )
 
+// maxRetryDelay - the longest pause between connection attempts
+const maxRetryDelay = 10 * time.Second
+
 type Client interface {
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
@@ -19,42 +24,141 @@ type Client interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
-// NewClient creates a new DB client for PostgreSQL server using pgx package
+// NewClient creates a new DB client for PostgreSQL server using pgx package. Connection is retried with backoff
+// for connect_retry seconds, so the service can start together with the database
 func NewClient(ctx context.Context, cfg config.Config) (pool *pgxpool.Pool, err error) {
-	// connect string
-	dsn := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s", cfg.Database.User, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+	poolConfig, err := PoolConfig(DSN(cfg), cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	//Create connection pool
-	pool, err = pgxpool.New(ctx, dsn)
+	pool, err = connect(ctx, poolConfig, time.Duration(cfg.Database.ConnectTimeout)*time.Second, time.Duration(cfg.Database.ConnectRetry)*time.Second)
 	if err != nil {
-		log.Fatal("Can not connect DB: error", err)
 		return nil, err
 	}
-
 	log.Println("DB client connected successfully")
 
 	// migrate DB
 	migrate, err := os.ReadFile("migrate.sql")
 	if err != nil {
-		log.Fatal("Can not read migrate.sql: error", err)
-		return nil, err
+		pool.Close()
+		return nil, fmt.Errorf("can not read migrate.sql: %w", err)
 	}
-	_, err = pool.Exec(ctx, string(migrate))
-	if err != nil {
-		log.Fatal("Can not migrate DB: error", err)
-		return nil, err
+	migrateCtx, cancel := withTimeout(ctx, time.Duration(cfg.Database.MigrateTimeout)*time.Second)
+	defer cancel()
+	if _, err = pool.Exec(migrateCtx, string(migrate)); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("can not migrate DB: %w", err)
 	}
 	log.Println("DB migrated successfully")
 
-	// ping
-	err = pool.Ping(ctx)
+	return pool, nil
+}
+
+// DSN builds connection string for PostgreSQL server from config
+func DSN(cfg config.Config) string {
+	db := cfg.Database
+	dsn := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(db.User, db.Password),
+		Host:   fmt.Sprintf("%s:%s", db.Host, db.Port),
+		Path:   db.DBName,
+	}
+
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"sslmode":     db.SSLMode,
+		"sslrootcert": db.SSLRootCert,
+		"sslcert":     db.SSLCert,
+		"sslkey":      db.SSLKey,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if db.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(db.ConnectTimeout))
+	}
+	dsn.RawQuery = query.Encode()
+	return dsn.String()
+}
+
+// PoolConfig parses dsn and applies pool settings from config, zero values keep pgxpool defaults
+func PoolConfig(dsn string, cfg config.Config) (*pgxpool.Config, error) {
+	db := cfg.Database
+	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
-		log.Fatal("Can not ping DB: error", err)
 		return nil, err
 	}
-	log.Println("DB pinged successfully")
 
+	if db.MaxConns > 0 {
+		poolConfig.MaxConns = int32(db.MaxConns)
+	}
+	if db.MinConns > 0 {
+		poolConfig.MinConns = int32(db.MinConns)
+	}
+	if db.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = time.Duration(db.MaxConnLifetime) * time.Second
+	}
+	if db.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = time.Duration(db.MaxConnIdleTime) * time.Second
+	}
+	if db.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = time.Duration(db.HealthCheckPeriod) * time.Second
+	}
+	if db.ApplicationName != "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = db.ApplicationName
+	}
+	if db.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.Itoa(db.StatementTimeout * 1000)
+	}
+	return poolConfig, nil
+}
+
+// connect creates pool and pings the server, failed attempts are repeated with growing delay until retry passes
+func connect(ctx context.Context, poolConfig *pgxpool.Config, timeout, retry time.Duration) (*pgxpool.Pool, error) {
+	deadline := time.Now().Add(retry)
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		pool, err := ping(ctx, poolConfig, timeout)
+		if err == nil {
+			return pool, nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("can not connect DB after %d attempts: %w", attempt, err)
+		}
+		log.Println("Can not connect DB, attempt", attempt, "retry in", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// ping creates pool and checks that the server answers within timeout
+func ping(ctx context.Context, poolConfig *pgxpool.Config, timeout time.Duration) (*pgxpool.Pool, error) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
 	return pool, nil
 }
+
+// withTimeout returns ctx limited by timeout, zero timeout means no limit
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weather_service/internal/config"
+)
+
+func TestPoolConfig(t *testing.T) {
+	var cfg config.Config
+	cfg.Database.Host = "db"
+	cfg.Database.Port = "5432"
+	cfg.Database.User = "postgres"
+	cfg.Database.Password = "p@ss:word/"
+	cfg.Database.DBName = "weather_service"
+	cfg.Database.SSLMode = "disable"
+	cfg.Database.ApplicationName = "weather_service"
+	cfg.Database.MaxConns = 20
+	cfg.Database.MaxConnLifetime = 3600
+	cfg.Database.StatementTimeout = 30
+	cfg.Database.ConnectTimeout = 3
+
+	poolConfig, err := PoolConfig(DSN(cfg), cfg)
+	if err != nil {
+		t.Fatalf("PoolConfig: %v", err)
+	}
+
+	conn := poolConfig.ConnConfig
+	if conn.Host != "db" || conn.Port != 5432 || conn.Password != "p@ss:word/" || conn.Database != "weather_service" {
+		t.Errorf("Expected connection from config, got %s:%d/%s with password %q", conn.Host, conn.Port, conn.Database, conn.Password)
+	}
+	if conn.TLSConfig != nil || conn.ConnectTimeout != 3*time.Second {
+		t.Errorf("Expected sslmode disable and 3s connect timeout, got %v, %v", conn.TLSConfig, conn.ConnectTimeout)
+	}
+	if poolConfig.MaxConns != 20 || poolConfig.MaxConnLifetime != time.Hour {
+		t.Errorf("Expected pool settings from config, got %d conns, %v lifetime", poolConfig.MaxConns, poolConfig.MaxConnLifetime)
+	}
+	if conn.RuntimeParams["application_name"] != "weather_service" || conn.RuntimeParams["statement_timeout"] != "30000" {
+		t.Errorf("Expected runtime params from config, got %v", conn.RuntimeParams)
+	}
+}
+
+func TestConnectGivesUp(t *testing.T) {
+	var cfg config.Config
+	cfg.Database.Host = "127.0.0.1"
+	cfg.Database.Port = "1"
+	cfg.Database.SSLMode = "disable"
+
+	poolConfig, err := PoolConfig(DSN(cfg), cfg)
+	if err != nil {
+		t.Fatalf("PoolConfig: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := connect(context.Background(), poolConfig, time.Second, 2*time.Second); err == nil {
+		t.Fatal("Expected error for unreachable server")
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 5*time.Second {
+		t.Errorf("Expected to retry for about 2 seconds, took %v", elapsed)
+	}
+}
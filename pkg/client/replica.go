@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+	"weather_service/internal/config"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReplicaClient sends queries to a read replica while it is reachable and lags behind primary no more than maxLag,
+// otherwise to primary. Exec and Begin always go to primary
+type ReplicaClient struct {
+	primary Client
+	replica Client
+	maxLag  time.Duration
+	ticker  *time.Ticker
+	fresh   atomic.Bool
+}
+
+// NewReplicaClient creates pool for replica dsn with pool settings of primary,
+// replica is used only after the first successful lag check in Start
+func NewReplicaClient(ctx context.Context, cfg config.Config, primary Client) (*ReplicaClient, error) {
+	replica := cfg.Database.Replica
+	poolConfig, err := PoolConfig(replica.DSN, cfg)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newReplicaClient(primary, pool, time.Duration(replica.MaxLag)*time.Second, time.Duration(replica.CheckInterval)*time.Second), nil
+}
+
+// newReplicaClient - constructor for ReplicaClient struct
+func newReplicaClient(primary, replica Client, maxLag, interval time.Duration) *ReplicaClient {
+	return &ReplicaClient{
+		primary: primary,
+		replica: replica,
+		maxLag:  maxLag,
+		ticker:  time.NewTicker(interval),
+	}
+}
+
+// CheckLag measures replication lag and decides where queries go. Fully replayed replica has no lag
+// even if primary had no writes for a while
+func (c *ReplicaClient) CheckLag(ctx context.Context) {
+	q := `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END::float8
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var seconds float64
+	if err := c.replica.QueryRow(ctx, q).Scan(&seconds); err != nil {
+		c.setFresh(false, "unavailable: "+err.Error())
+		return
+	}
+	lag := time.Duration(seconds * float64(time.Second))
+	if lag > c.maxLag {
+		c.setFresh(false, "lags "+lag.String())
+		return
+	}
+	c.setFresh(true, "lags "+lag.String())
+}
+
+// setFresh switches reads and logs only when the state changes
+func (c *ReplicaClient) setFresh(fresh bool, reason string) {
+	if c.fresh.Swap(fresh) == fresh {
+		return
+	}
+	if fresh {
+		log.Println("Read replica is used for queries:", reason)
+	} else {
+		log.Println("Read replica is not used, queries go to primary:", reason)
+	}
+}
+
+// Start - checks lag now and then in background with {interval}
+func (c *ReplicaClient) Start() {
+	c.CheckLag(context.Background())
+	go func() {
+		defer c.ticker.Stop()
+		for {
+			select {
+			case <-c.ticker.C:
+				c.CheckLag(context.Background())
+			}
+		}
+	}()
+}
+
+// Stop - stops lag checks
+func (c *ReplicaClient) Stop() {
+	c.ticker.Stop()
+}
+
+// Exec always runs on primary
+func (c *ReplicaClient) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	return c.primary.Exec(ctx, sql, arguments...)
+}
+
+// Begin always starts transaction on primary
+func (c *ReplicaClient) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.primary.Begin(ctx)
+}
+
+// Query runs on replica when it is fresh, connection errors of replica are retried on primary
+func (c *ReplicaClient) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if !c.fresh.Load() {
+		return c.primary.Query(ctx, sql, args...)
+	}
+	rows, err := c.replica.Query(ctx, sql, args...)
+	if err != nil && isConnectionError(err) {
+		c.setFresh(false, "unavailable: "+err.Error())
+		return c.primary.Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+// QueryRow runs on replica when it is fresh, connection errors of replica are retried on primary
+func (c *ReplicaClient) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if !c.fresh.Load() {
+		return c.primary.QueryRow(ctx, sql, args...)
+	}
+	return &replicaRow{client: c, row: c.replica.QueryRow(ctx, sql, args...), ctx: ctx, sql: sql, args: args}
+}
+
+// replicaRow retries query on primary when scanning replica row fails with connection error
+type replicaRow struct {
+	client *ReplicaClient
+	row    pgx.Row
+	ctx    context.Context
+	sql    string
+	args   []interface{}
+}
+
+// Scan reads row of replica or primary
+func (r *replicaRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err != nil && isConnectionError(err) {
+		r.client.setFresh(false, "unavailable: "+err.Error())
+		return r.client.primary.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	}
+	return err
+}
+
+// isConnectionError reports whether err is a failure to reach the server rather than its answer,
+// reads are idempotent so such queries can be repeated on primary
+func isConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) || pgconn.SafeToRetry(err)
+}
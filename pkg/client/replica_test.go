@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeClient answers every QueryRow with value or err and counts queries
+type fakeClient struct {
+	value   float64
+	err     error
+	queries int
+}
+
+func (c *fakeClient) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *fakeClient) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	c.queries++
+	return nil, c.err
+}
+
+func (c *fakeClient) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	c.queries++
+	return fakeRow{value: c.value, err: c.err}
+}
+
+func (c *fakeClient) Begin(ctx context.Context) (pgx.Tx, error) {
+	return nil, nil
+}
+
+type fakeRow struct {
+	value float64
+	err   error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*float64) = r.value
+	return nil
+}
+
+func TestReplicaClientRouting(t *testing.T) {
+	ctx := context.Background()
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name        string
+		lag         float64
+		checkErr    error
+		queryErr    error
+		wantReplica int
+		wantPrimary int
+	}{
+		{name: "fresh replica", lag: 1, wantReplica: 1},
+		{name: "lagging replica", lag: 60, wantPrimary: 1},
+		{name: "unavailable replica", checkErr: refused, wantPrimary: 1},
+		{name: "replica fails after check", lag: 1, queryErr: refused, wantReplica: 1, wantPrimary: 1},
+		{name: "SQL error is not retried", lag: 1, queryErr: &pgconn.PgError{Code: "42P01"}, wantReplica: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &fakeClient{}
+			replica := &fakeClient{value: tt.lag, err: tt.checkErr}
+			c := newReplicaClient(primary, replica, 30*time.Second, time.Hour)
+			defer c.Stop()
+
+			c.CheckLag(ctx)
+			replica.queries = 0
+			replica.err = tt.queryErr
+			if tt.checkErr != nil {
+				replica.err = tt.checkErr
+			}
+
+			var value float64
+			_ = c.QueryRow(ctx, "SELECT 1").Scan(&value)
+
+			if replica.queries != tt.wantReplica || primary.queries != tt.wantPrimary {
+				t.Errorf("Expected %d replica and %d primary queries, got %d and %d",
+					tt.wantReplica, tt.wantPrimary, replica.queries, primary.queries)
+			}
+		})
+	}
+
+	t.Run("replica is not used after connection error", func(t *testing.T) {
+		primary := &fakeClient{}
+		replica := &fakeClient{value: 1}
+		c := newReplicaClient(primary, replica, 30*time.Second, time.Hour)
+		defer c.Stop()
+
+		c.CheckLag(ctx)
+		replica.queries = 0
+		replica.err = refused
+		if _, err := c.Query(ctx, "SELECT 1"); err != nil {
+			t.Fatalf("Expected query to be retried on primary, got %v", err)
+		}
+		replica.err = errors.New("must not be called")
+		c.Query(ctx, "SELECT 1")
+		if replica.queries != 1 || primary.queries != 2 {
+			t.Errorf("Expected primary to serve queries until next check, got %d replica and %d primary queries", replica.queries, primary.queries)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+	"weather_service/internal/config"
+
+	_ "modernc.org/sqlite"
+)
+
+// NewSQLiteClient opens SQLite database file from config and migrates it
+func NewSQLiteClient(ctx context.Context, cfg config.Config) (*sql.DB, error) {
+	// foreign keys are off by default in SQLite, immediate transactions wait for the write lock instead of failing
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", cfg.SQLite.Path)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// ping
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Println("SQLite database opened successfully:", cfg.SQLite.Path)
+
+	// migrate DB
+	migrate, err := os.ReadFile("migrate_sqlite.sql")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, string(migrate)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addSQLiteColumns(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Println("SQLite database migrated successfully")
+
+	return db, nil
+}
+
+// sqliteColumns - columns added to tables after they were first created, CREATE TABLE IF NOT EXISTS
+// does not change tables of existing database files
+var sqliteColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"daily_summaries", "gust_max", "REAL NOT NULL DEFAULT 0"},
+	{"daily_summaries", "icon", "TEXT NOT NULL DEFAULT ''"},
+	{"daily_summaries", "partial", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// addSQLiteColumns adds missing columns of sqliteColumns, SQLite has no ADD COLUMN IF NOT EXISTS
+func addSQLiteColumns(ctx context.Context, db *sql.DB) error {
+	for _, c := range sqliteColumns {
+		var exists bool
+		q := "SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)"
+		if err := db.QueryRowContext(ctx, q, c.table, c.column).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+			return err
+		}
+		log.Println("SQLite column added:", c.table+"."+c.column)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestAddSQLiteColumns(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// daily_summaries of a database file created before gust_max and icon
+	if _, err := db.ExecContext(ctx, `CREATE TABLE daily_summaries (city_id INTEGER NOT NULL, date TEXT NOT NULL, temp_min REAL NOT NULL)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO daily_summaries (city_id, date, temp_min) VALUES (1, '2024-07-11', 12)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// the second run finds the columns and changes nothing
+	for i := 0; i < 2; i++ {
+		if err := addSQLiteColumns(ctx, db); err != nil {
+			t.Fatalf("addSQLiteColumns run %d: %v", i+1, err)
+		}
+	}
+
+	var gustMax float64
+	var icon string
+	if err := db.QueryRowContext(ctx, `SELECT gust_max, icon FROM daily_summaries WHERE city_id = 1`).Scan(&gustMax, &icon); err != nil {
+		t.Fatalf("select added columns: %v", err)
+	}
+	if gustMax != 0 || icon != "" {
+		t.Errorf("Expected defaults for existing rows, got %v, %q", gustMax, icon)
+	}
+}
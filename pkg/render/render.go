@@ -0,0 +1,253 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Formats of response body, selected by format query parameter or Accept header
+const (
+	FormatJSON       = "json"
+	FormatPrettyJSON = "pretty"
+	FormatCSV        = "csv"
+	FormatXML        = "xml"
+	FormatMsgPack    = "msgpack"
+)
+
+// contentTypes - content type of every format
+var contentTypes = map[string]string{
+	FormatJSON:       "application/json",
+	FormatPrettyJSON: "application/json",
+	FormatCSV:        "text/csv; charset=utf-8",
+	FormatXML:        "application/xml; charset=utf-8",
+	FormatMsgPack:    "application/msgpack",
+}
+
+// mediaTypes - formats of media types in Accept header, JSON is indented like before content negotiation
+var mediaTypes = map[string]string{
+	"application/json":        FormatPrettyJSON,
+	"text/csv":                FormatCSV,
+	"application/xml":         FormatXML,
+	"text/xml":                FormatXML,
+	"application/msgpack":     FormatMsgPack,
+	"application/x-msgpack":   FormatMsgPack,
+	"application/vnd.msgpack": FormatMsgPack,
+}
+
+var (
+	// ErrUnknownFormat - format query parameter names no supported format
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrNotAcceptable - no media type of Accept header can be produced
+	ErrNotAcceptable = errors.New("not acceptable")
+)
+
+// Tabular is implemented by responses that can be written as CSV, every row has a value for every header column
+type Tabular interface {
+	Table() (header []string, rows [][]string)
+}
+
+// Negotiate returns format of the response: format query parameter wins, then the most preferred
+// supported media type of Accept header. Without both, or for */*, JSON is indented
+func Negotiate(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := contentTypes[format]; !ok {
+			return "", fmt.Errorf("%w %q: expected json, pretty, csv, xml or msgpack", ErrUnknownFormat, format)
+		}
+		return format, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatPrettyJSON, nil
+	}
+
+	type candidate struct {
+		format string
+		q      float64
+	}
+	candidates := make([]candidate, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		switch format, ok := mediaTypes[mediaType]; {
+		case ok:
+			candidates = append(candidates, candidate{format: format, q: q})
+		case mediaType == "*/*" || mediaType == "application/*":
+			candidates = append(candidates, candidate{format: FormatPrettyJSON, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w: %s, expected application/json, text/csv, application/xml or application/msgpack", ErrNotAcceptable, accept)
+	}
+	// the first listed type wins among equally preferred ones
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].format, nil
+}
+
+// ContentType returns content type of format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Encode returns data in format, CSV needs data implementing Tabular and returns ErrNotAcceptable otherwise.
+// XML and MessagePack use field names of JSON
+func Encode(format string, data interface{}) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.Marshal(data)
+	case FormatPrettyJSON:
+		return json.MarshalIndent(data, "", "  ")
+	case FormatCSV:
+		table, ok := data.(Tabular)
+		if !ok {
+			return nil, fmt.Errorf("%w: CSV is not available for this resource", ErrNotAcceptable)
+		}
+		return encodeCSV(table)
+	case FormatXML:
+		return encodeXML(data)
+	case FormatMsgPack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// encodeCSV writes header and rows of table
+func encodeCSV(table Tabular) ([]byte, error) {
+	header, rows := table.Table()
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeXML converts JSON form of data to XML keeping the order of fields: objects become elements named
+// by their keys, array items become item elements and null becomes an empty element
+func encodeXML(data interface{}) ([]byte, error) {
+	document, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(document))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := writeXMLElement(enc, dec, "response"); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXMLElement writes the next JSON value of dec as element name
+func writeXMLElement(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		for dec.More() {
+			child := "item"
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = key.(string)
+			}
+			if err := writeXMLElement(enc, dec, child); err != nil {
+				return err
+			}
+		}
+		// closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(t))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName makes JSON key a valid XML element name, keys like 3h get underscore prefix
+func xmlName(key string) string {
+	if key == "" {
+		return "_"
+	}
+	var b strings.Builder
+	for i, c := range key {
+		valid := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			i > 0 && (c == '-' || c == '.' || c >= '0' && c <= '9')
+		if i == 0 && c >= '0' && c <= '9' {
+			b.WriteByte('_')
+			valid = true
+		}
+		if !valid {
+			c = '_'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Float formats number for CSV without trailing zeros
+func Float(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Int formats integer for CSV
+func Int(v int) string {
+	return strconv.Itoa(v)
+}
+
+// Time formats time for CSV in RFC 3339
+func Time(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type slot struct {
+	Time string  `json:"time"`
+	Temp float64 `json:"temp"`
+}
+
+type slots []slot
+
+func (s slots) Table() ([]string, [][]string) {
+	rows := make([][]string, 0, len(s))
+	for _, sl := range s {
+		rows = append(rows, []string{sl.Time, Float(sl.Temp)})
+	}
+	return []string{"time", "temp"}, rows
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		url    string
+		accept string
+		format string
+		err    error
+	}{
+		{"/", "", FormatPrettyJSON, nil},
+		{"/", "*/*", FormatPrettyJSON, nil},
+		{"/", "text/csv", FormatCSV, nil},
+		{"/", "application/json;q=0.5, application/xml", FormatXML, nil},
+		{"/", "text/html, application/msgpack;q=0.1", FormatMsgPack, nil},
+		{"/?format=json", "text/csv", FormatJSON, nil},
+		{"/?format=yaml", "", "", ErrUnknownFormat},
+		{"/", "text/html", "", ErrNotAcceptable},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.url, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		format, err := Negotiate(r)
+		if !errors.Is(err, tt.err) || format != tt.format {
+			t.Errorf("%s with Accept %q: expected %q, %v, got %q, %v", tt.url, tt.accept, tt.format, tt.err, format, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	data := slots{{Time: "2024-07-13T12:00:00Z", Temp: 21.5}, {Time: "2024-07-13T15:00:00Z", Temp: 23}}
+
+	body, err := Encode(FormatCSV, data)
+	if err != nil {
+		t.Fatalf("Encode csv: %v", err)
+	}
+	if expected := "time,temp\n2024-07-13T12:00:00Z,21.5\n2024-07-13T15:00:00Z,23\n"; string(body) != expected {
+		t.Errorf("expected csv %q, got %q", expected, body)
+	}
+
+	body, err = Encode(FormatXML, map[string]interface{}{"city_id": 1, "3h": nil, "slots": data})
+	if err != nil {
+		t.Fatalf("Encode xml: %v", err)
+	}
+	for _, expected := range []string{"<response>", "<_3h></_3h>", "<city_id>1</city_id>", "<item>", "<temp>21.5</temp>"} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected %s in xml:\n%s", expected, body)
+		}
+	}
+
+	body, err = Encode(FormatMsgPack, data)
+	if err != nil {
+		t.Fatalf("Encode msgpack: %v", err)
+	}
+	var decoded []map[string]interface{}
+	if err := msgpack.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("Unmarshal msgpack: %v", err)
+	}
+	if len(decoded) != 2 || decoded[1]["temp"] != 23.0 {
+		t.Errorf("expected json field names in msgpack, got %v", decoded)
+	}
+
+	if _, err := Encode(FormatCSV, map[string]int{"id": 1}); !errors.Is(err, ErrNotAcceptable) {
+		t.Errorf("expected ErrNotAcceptable for csv of non tabular data, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts - accepted ISO 8601 timestamps with timezone, seconds may be omitted
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
+
+// ParseTime parses query time parameter in RFC 3339 format or as a date (midnight UTC)
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339 timestamp or YYYY-MM-DD date", value)
+}
+
+// ParseRelativeTime parses query time parameter like ParseTime and also accepts now and offsets from now
+// like +24h, -90m or +2d
+func ParseRelativeTime(value string, now time.Time) (time.Time, error) {
+	// unescaped + in query string is decoded as space
+	if strings.HasPrefix(value, " ") {
+		value = "+" + strings.TrimLeft(value, " ")
+	}
+	if value == "now" {
+		return now.UTC(), nil
+	}
+	if !strings.HasPrefix(value, "+") && !strings.HasPrefix(value, "-") {
+		return ParseTime(value)
+	}
+
+	offset, err := parseOffset(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected now, offset like +24h or RFC 3339 timestamp", value)
+	}
+	return now.Add(offset).UTC(), nil
+}
+
+// parseOffset parses signed duration, d suffix stands for 24 hours
+func parseOffset(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// ParseTimeRange parses from and to query parameters, empty values default to the {defaultSpan} before now
+func ParseTimeRange(fromValue, toValue string, defaultSpan time.Duration) (from, to time.Time, err error) {
+	to = time.Now().UTC()
+	if toValue != "" {
+		if to, err = ParseTime(toValue); err != nil {
+			return from, to, err
+		}
+	}
+	from = to.Add(-defaultSpan)
+	if fromValue != "" {
+		if from, err = ParseTime(fromValue); err != nil {
+			return from, to, err
+		}
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+// MaxRangeSpan - longest time range of forecast queries, forecasts do not reach further than 16 days
+const MaxRangeSpan = 16 * 24 * time.Hour
+
+// ParseUpcomingRange parses from and to query parameters that may be relative to now,
+// empty from defaults to now and empty to to the {defaultSpan} after from. Ranges longer than MaxRangeSpan are rejected
+func ParseUpcomingRange(fromValue, toValue string, defaultSpan time.Duration) (from, to time.Time, err error) {
+	now := time.Now().UTC()
+	from = now
+	if fromValue != "" {
+		if from, err = ParseRelativeTime(fromValue, now); err != nil {
+			return from, to, err
+		}
+	}
+	to = from.Add(defaultSpan)
+	if toValue != "" {
+		if to, err = ParseRelativeTime(toValue, now); err != nil {
+			return from, to, err
+		}
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > MaxRangeSpan {
+		return from, to, fmt.Errorf("time range must not be longer than %d days", MaxRangeSpan/(24*time.Hour))
+	}
+	return from, to, nil
+}